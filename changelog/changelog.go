@@ -0,0 +1,299 @@
+package changelog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrGroupInvalid   = errors.New("invalid changelog group")
+	ErrExcludeInvalid = errors.New("invalid changelog exclude pattern")
+	ErrShallowClone   = errors.New("shallow clone, the changelog requires the full history and tags")
+
+	errNoPreviousTag = errors.New("no previous tag")
+)
+
+// GroupAny is the commit type that matches all commits not matched by another group.
+const GroupAny = "*"
+
+const (
+	fieldSeparator  = "\x1f"
+	recordSeparator = "\x1e"
+	logFields       = 3
+	shortSHALength  = 7
+)
+
+var conventionalCommitRe = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+
+// Options for generating a changelog.
+type Options struct {
+	// Dir is the path of the local git repository.
+	Dir string
+	// Tag is the tag of the current release. If the tag does not exist
+	// in the local repository, HEAD is used instead.
+	Tag string
+	// Groups defines the sections of the changelog in the order they are rendered.
+	Groups []Group
+	// Exclude is a list of patterns matched against the commit subject.
+	Exclude []*regexp.Regexp
+}
+
+// Group is a changelog section for one or more conventional commit types.
+type Group struct {
+	Title string
+	Types []string
+}
+
+// Commit is a parsed conventional commit.
+type Commit struct {
+	SHA         string
+	Type        string
+	Scope       string
+	Description string
+	Breaking    bool
+}
+
+// ParseGroups parses groups in the format "<type>[|<type>...]:<title>", e.g. "fix|perf:Bug Fixes".
+// The type "*" matches all commits that are not matched by any other group.
+func ParseGroups(groups []string) ([]Group, error) {
+	result := make([]Group, 0, len(groups))
+
+	for _, group := range groups {
+		types, title, ok := strings.Cut(group, ":")
+		if !ok || strings.TrimSpace(types) == "" || strings.TrimSpace(title) == "" {
+			return nil, fmt.Errorf("%w: %q", ErrGroupInvalid, group)
+		}
+
+		g := Group{Title: strings.TrimSpace(title)}
+
+		for _, t := range strings.Split(types, "|") {
+			if t = strings.TrimSpace(t); t != "" {
+				g.Types = append(g.Types, t)
+			}
+		}
+
+		result = append(result, g)
+	}
+
+	return result, nil
+}
+
+// ParseExclude compiles the given list of regular expressions.
+func ParseExclude(patterns []string) ([]*regexp.Regexp, error) {
+	result := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrExcludeInvalid, pattern, err)
+		}
+
+		result = append(result, re)
+	}
+
+	return result, nil
+}
+
+// Generate reads the commits between the previous tag and the current tag from the
+// local git repository and renders them as Markdown, grouped by conventional commit type.
+// The repository has to be a full clone including tags, shallow clones are rejected.
+// If no previous tag exists, all commits up to the current tag are included.
+func Generate(opt Options) (string, error) {
+	shallow, err := gitOutput(opt.Dir, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return "", fmt.Errorf("failed to read git repository: %w", err)
+	}
+
+	if strings.TrimSpace(shallow) == "true" {
+		return "", ErrShallowClone
+	}
+
+	ref := "HEAD"
+	if opt.Tag != "" && git(opt.Dir, "rev-parse", "--verify", "--quiet", "refs/tags/"+opt.Tag) == nil {
+		ref = opt.Tag
+	}
+
+	commitRange := ref
+
+	prev, err := previousTag(opt.Dir, ref)
+
+	switch {
+	case err == nil:
+		commitRange = prev + ".." + ref
+	case errors.Is(err, errNoPreviousTag):
+		log.Warn().Msgf("no tag found before %s, the changelog contains all commits", ref)
+	default:
+		return "", fmt.Errorf("failed to find previous tag: %w", err)
+	}
+
+	out, err := gitOutput(opt.Dir, "log", "--no-merges", "--format=%H"+fieldSeparator+"%s"+fieldSeparator+"%b"+recordSeparator,
+		commitRange)
+	if err != nil {
+		return "", fmt.Errorf("failed to read git log: %w", err)
+	}
+
+	commits := make([]Commit, 0)
+
+	for _, record := range strings.Split(out, recordSeparator) {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+
+		fields := strings.SplitN(record, fieldSeparator, logFields)
+		if len(fields) != logFields {
+			continue
+		}
+
+		if excluded(fields[1], opt.Exclude) {
+			continue
+		}
+
+		if commit, ok := ParseCommit(fields[0], fields[1], fields[2]); ok {
+			commits = append(commits, commit)
+		}
+	}
+
+	return Render(commits, opt.Groups), nil
+}
+
+// ParseCommit parses a commit subject and body according to the conventional commit specification.
+// It returns false if the subject is not a conventional commit.
+func ParseCommit(sha, subject, body string) (Commit, bool) {
+	m := conventionalCommitRe.FindStringSubmatch(strings.TrimSpace(subject))
+	if m == nil {
+		return Commit{}, false
+	}
+
+	return Commit{
+		SHA:         sha,
+		Type:        strings.ToLower(m[1]),
+		Scope:       m[2],
+		Description: m[4],
+		Breaking:    m[3] == "!" || strings.Contains(body, "BREAKING CHANGE:") || strings.Contains(body, "BREAKING-CHANGE:"),
+	}, true
+}
+
+// Render renders the given commits as Markdown sections in the order of the groups.
+// Commits that do not match any group are omitted.
+func Render(commits []Commit, groups []Group) string {
+	sections := make([][]Commit, len(groups))
+
+	for _, commit := range commits {
+		idx := slices.IndexFunc(groups, func(g Group) bool {
+			return slices.Contains(g.Types, commit.Type)
+		})
+
+		if idx < 0 {
+			idx = slices.IndexFunc(groups, func(g Group) bool {
+				return slices.Contains(g.Types, GroupAny)
+			})
+		}
+
+		if idx < 0 {
+			continue
+		}
+
+		sections[idx] = append(sections[idx], commit)
+	}
+
+	var buf bytes.Buffer
+
+	for i, group := range groups {
+		if len(sections[i]) == 0 {
+			continue
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+
+		fmt.Fprintf(&buf, "### %s\n\n", group.Title)
+
+		for _, commit := range sections[i] {
+			buf.WriteString("- ")
+
+			if commit.Breaking {
+				buf.WriteString("**BREAKING** ")
+			}
+
+			if commit.Scope != "" {
+				fmt.Fprintf(&buf, "**%s:** ", commit.Scope)
+			}
+
+			buf.WriteString(commit.Description)
+
+			if len(commit.SHA) >= shortSHALength {
+				fmt.Fprintf(&buf, " (%s)", commit.SHA[:shortSHALength])
+			}
+
+			buf.WriteString("\n")
+		}
+	}
+
+	return strings.TrimSpace(buf.String())
+}
+
+// previousTag returns the closest tag reachable from the parent of ref. It returns
+// errNoPreviousTag if ref is the first commit or no tag is reachable.
+func previousTag(dir, ref string) (string, error) {
+	if git(dir, "rev-parse", "--verify", "--quiet", ref+"^") != nil {
+		return "", errNoPreviousTag
+	}
+
+	tags, err := gitOutput(dir, "tag", "--merged", ref+"^")
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(tags) == "" {
+		return "", errNoPreviousTag
+	}
+
+	out, err := gitOutput(dir, "describe", "--tags", "--abbrev=0", ref+"^")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(out), nil
+}
+
+func excluded(subject string, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if re.MatchString(subject) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func git(dir string, args ...string) error {
+	_, err := gitOutput(dir, args...)
+
+	return err
+}
+
+// gitOutput runs git in the given directory and returns the output. The directory is
+// marked as safe, as CI workspaces are often owned by a different user than the plugin.
+func gitOutput(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-c", "safe.directory=*"}, args...)...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,236 @@
+package changelog
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  []string
+		want    []Group
+		wantErr error
+	}{
+		{
+			name:   "single type",
+			groups: []string{"feat:Features"},
+			want:   []Group{{Title: "Features", Types: []string{"feat"}}},
+		},
+		{
+			name:   "multiple types",
+			groups: []string{"fix|perf:Bug Fixes", "*:Others"},
+			want: []Group{
+				{Title: "Bug Fixes", Types: []string{"fix", "perf"}},
+				{Title: "Others", Types: []string{"*"}},
+			},
+		},
+		{
+			name:    "missing title",
+			groups:  []string{"feat:"},
+			wantErr: ErrGroupInvalid,
+		},
+		{
+			name:    "missing separator",
+			groups:  []string{"Features"},
+			wantErr: ErrGroupInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGroups(tt.groups)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		body    string
+		want    Commit
+		wantOk  bool
+	}{
+		{
+			name:    "type only",
+			subject: "feat: add option",
+			want:    Commit{Type: "feat", Description: "add option"},
+			wantOk:  true,
+		},
+		{
+			name:    "type and scope",
+			subject: "fix(gitea): handle pagination",
+			want:    Commit{Type: "fix", Scope: "gitea", Description: "handle pagination"},
+			wantOk:  true,
+		},
+		{
+			name:    "breaking marker",
+			subject: "feat(api)!: drop old endpoint",
+			want:    Commit{Type: "feat", Scope: "api", Description: "drop old endpoint", Breaking: true},
+			wantOk:  true,
+		},
+		{
+			name:    "breaking footer",
+			subject: "refactor: rename settings",
+			body:    "BREAKING CHANGE: settings were renamed",
+			want:    Commit{Type: "refactor", Description: "rename settings", Breaking: true},
+			wantOk:  true,
+		},
+		{
+			name:    "no conventional commit",
+			subject: "Merge branch 'main'",
+			wantOk:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseCommit("", tt.subject, tt.body)
+
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+
+	runGit(t, dir, "init", "--quiet")
+	commit(t, dir, "feat: initial feature")
+	runGit(t, dir, "tag", "v1.0.0")
+	commit(t, dir, "feat(cli): add flag")
+	commit(t, dir, "fix: correct typo")
+	commit(t, dir, "chore(deps): update module")
+	commit(t, dir, "docs: update readme")
+	commit(t, dir, "unconventional commit")
+	runGit(t, dir, "tag", "v1.1.0")
+	commit(t, dir, "feat!: remove deprecated flag")
+
+	groups := []Group{
+		{Title: "Features", Types: []string{"feat"}},
+		{Title: "Bug Fixes", Types: []string{"fix"}},
+		{Title: "Others", Types: []string{GroupAny}},
+	}
+
+	tests := []struct {
+		name    string
+		opt     Options
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "commits between tags",
+			opt: Options{
+				Dir:     dir,
+				Tag:     "v1.1.0",
+				Groups:  groups,
+				Exclude: []*regexp.Regexp{regexp.MustCompile(`^chore\(deps\)`)},
+			},
+			want: []string{
+				"### Features\n\n- **cli:** add flag (",
+				"### Bug Fixes\n\n- correct typo (",
+				"### Others\n\n- update readme (",
+			},
+			notWant: []string{"initial feature", "update module", "unconventional commit", "remove deprecated flag"},
+		},
+		{
+			name: "unknown tag uses HEAD",
+			opt: Options{
+				Dir:    dir,
+				Tag:    "v2.0.0",
+				Groups: groups[:1],
+			},
+			want:    []string{"### Features\n\n- **BREAKING** remove deprecated flag ("},
+			notWant: []string{"add flag", "correct typo"},
+		},
+		{
+			name: "first tag includes all commits",
+			opt: Options{
+				Dir:    dir,
+				Tag:    "v1.0.0",
+				Groups: groups,
+			},
+			want: []string{"- initial feature ("},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Generate(tt.opt)
+			require.NoError(t, err)
+
+			for _, s := range tt.want {
+				assert.Contains(t, got, s)
+			}
+
+			for _, s := range tt.notWant {
+				assert.NotContains(t, got, s)
+			}
+		})
+	}
+}
+
+func TestGenerateShallowClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	clone := t.TempDir()
+
+	runGit(t, dir, "init", "--quiet")
+	commit(t, dir, "feat: initial feature")
+	runGit(t, dir, "tag", "v1.0.0")
+	commit(t, dir, "fix: correct typo")
+	runGit(t, clone, "clone", "--quiet", "--depth", "1", "file://"+dir, ".")
+
+	_, err := Generate(Options{Dir: clone, Tag: "v1.1.0"})
+	assert.ErrorIs(t, err, ErrShallowClone)
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+dir,
+	)
+
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func commit(t *testing.T, dir, message string) {
+	t.Helper()
+
+	name := filepath.Join(dir, "file.txt")
+	content, _ := os.ReadFile(name)
+
+	require.NoError(t, os.WriteFile(name, append(content, []byte(message+"\n")...), 0o600))
+
+	runGit(t, dir, "add", "file.txt")
+	runGit(t, dir, "commit", "--quiet", "--no-gpg-sign", "-m", message)
+}
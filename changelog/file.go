@@ -0,0 +1,99 @@
+package changelog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrSectionNotFound = errors.New("changelog section not found")
+	ErrHeadingInvalid  = errors.New("invalid changelog heading pattern")
+)
+
+// VersionPlaceholder is replaced by the quoted version in heading patterns.
+const VersionPlaceholder = "{version}"
+
+// DefaultHeading matches the release headings of the most common changelog dialects, e.g.
+// "## [1.2.3] - 2024-01-31" (Keep a Changelog), "## [1.2.3](https://...) (2024-01-31)"
+// (conventional-changelog), "## v1.2.3" and "## Version 1.2.3".
+const DefaultHeading = `(?i)^\[?(?:version\s+)?v?{version}\]?(?:[\s(]|$)`
+
+var headingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+// ExtractSection returns the content below the first Markdown heading that matches the given
+// version up to the next heading of the same or a higher level. The version is matched with
+// and without a leading "v". Patterns are regular expressions matched against the heading text,
+// where "{version}" is replaced by the version. If no pattern is given, DefaultHeading is used.
+func ExtractSection(r io.Reader, version string, patterns []string) (string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{DefaultHeading}
+	}
+
+	quoted := regexp.QuoteMeta(strings.TrimPrefix(version, "v"))
+	matchers := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(strings.ReplaceAll(pattern, VersionPlaceholder, quoted))
+		if err != nil {
+			return "", fmt.Errorf("%w: %q: %w", ErrHeadingInvalid, pattern, err)
+		}
+
+		matchers = append(matchers, re)
+	}
+
+	var (
+		section []string
+		level   int
+		inCode  bool
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+
+		m := headingRe.FindStringSubmatch(line)
+		if m == nil || inCode {
+			if level > 0 {
+				section = append(section, line)
+			}
+
+			continue
+		}
+
+		if level > 0 {
+			if len(m[1]) <= level {
+				break
+			}
+
+			section = append(section, line)
+
+			continue
+		}
+
+		for _, re := range matchers {
+			if re.MatchString(m[2]) {
+				level = len(m[1])
+
+				break
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read changelog: %w", err)
+	}
+
+	if level == 0 {
+		return "", fmt.Errorf("%w: %s", ErrSectionNotFound, version)
+	}
+
+	return strings.TrimSpace(strings.Join(section, "\n")), nil
+}
@@ -0,0 +1,135 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractSection(t *testing.T) {
+	tests := []struct {
+		name      string
+		changelog string
+		version   string
+		patterns  []string
+		want      string
+		wantErr   error
+	}{
+		{
+			name: "keep a changelog",
+			changelog: `# Changelog
+
+## [Unreleased]
+
+## [1.2.0] - 2024-02-01
+
+### Added
+
+- New flag
+
+## [1.1.0] - 2024-01-01
+
+### Fixed
+
+- Old bug
+`,
+			version: "v1.2.0",
+			want:    "### Added\n\n- New flag",
+		},
+		{
+			name: "conventional changelog",
+			changelog: `# [1.2.0](https://example.com/compare/v1.1.0...v1.2.0) (2024-02-01)
+
+### Features
+
+* new flag ([abc1234](https://example.com/commit/abc1234))
+
+# [1.1.0](https://example.com/compare/v1.0.0...v1.1.0) (2024-01-01)
+`,
+			version: "1.2.0",
+			want:    "### Features\n\n* new flag ([abc1234](https://example.com/commit/abc1234))",
+		},
+		{
+			name: "v prefixed heading",
+			changelog: `## v1.2.0
+
+- New flag
+
+## v1.1.0
+
+- Old bug
+`,
+			version: "1.2.0",
+			want:    "- New flag",
+		},
+		{
+			name: "version prefixed heading",
+			changelog: `## Version 1.2.0 (2024-02-01)
+
+- New flag
+`,
+			version: "v1.2.0",
+			want:    "- New flag",
+		},
+		{
+			name: "does not match version prefix",
+			changelog: `## [1.2.0-rc.1] - 2024-01-20
+
+- Release candidate
+
+## [1.2.0] - 2024-02-01
+
+- Final release
+`,
+			version: "v1.2.0",
+			want:    "- Final release",
+		},
+		{
+			name:      "ignores headings in code blocks",
+			changelog: "## 1.2.0\n\n```markdown\n## 1.1.0\n```\n\n- New flag\n\n## 1.1.0\n",
+			version:   "v1.2.0",
+			want:      "```markdown\n## 1.1.0\n```\n\n- New flag",
+		},
+		{
+			name: "custom heading pattern",
+			changelog: `Release {1.2.0}
+=====
+
+## Release 1.2.0
+
+- New flag
+`,
+			version:  "v1.2.0",
+			patterns: []string{`^Release {version}$`},
+			want:     "- New flag",
+		},
+		{
+			name:      "missing section",
+			changelog: "## [1.1.0] - 2024-01-01\n\n- Old bug\n",
+			version:   "v1.2.0",
+			wantErr:   ErrSectionNotFound,
+		},
+		{
+			name:      "invalid pattern",
+			changelog: "## [1.2.0]\n",
+			version:   "v1.2.0",
+			patterns:  []string{"^[{version}"},
+			wantErr:   ErrHeadingInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractSection(strings.NewReader(tt.changelog), tt.version, tt.patterns)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
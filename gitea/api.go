@@ -9,7 +9,10 @@ import (
 //nolint:lll
 type APIClient interface {
 	ListReleases(owner, repo string, opt gitea.ListReleasesOptions) ([]*gitea.Release, *gitea.Response, error)
+	GetReleaseByTag(owner, repo, tag string) (*gitea.Release, *gitea.Response, error)
 	CreateRelease(owner, repo string, opt gitea.CreateReleaseOption) (*gitea.Release, *gitea.Response, error)
+	EditRelease(owner, repo string, id int64, form gitea.EditReleaseOption) (*gitea.Release, *gitea.Response, error)
+	DeleteRelease(user, repo string, id int64) (*gitea.Response, error)
 	ListReleaseAttachments(user, repo string, release int64, opt gitea.ListReleaseAttachmentsOptions) ([]*gitea.Attachment, *gitea.Response, error)
 	CreateReleaseAttachment(user, repo string, release int64, file io.Reader, filename string) (*gitea.Attachment, *gitea.Response, error)
 	DeleteReleaseAttachment(user, repo string, release, id int64) (*gitea.Response, error)
@@ -0,0 +1,231 @@
+package gitea
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/rs/zerolog/log"
+)
+
+// DryRunClient wraps an APIClient and only passes read requests to it. Requests that
+// would modify releases or attachments are recorded as plan and answered with the
+// expected result instead, so that the calling code follows the same path as in a real run.
+type DryRunClient struct {
+	client APIClient
+
+	mu          sync.Mutex
+	plan        []string
+	releases    map[int64]*gitea.Release
+	attachments map[int64]string
+	nextID      int64
+}
+
+var (
+	_ APIClient = (*DryRunClient)(nil)
+	_ planner   = (*DryRunClient)(nil)
+)
+
+// NewDryRunClient creates a new DryRunClient that wraps the given APIClient.
+func NewDryRunClient(client APIClient) *DryRunClient {
+	return &DryRunClient{
+		client:      client,
+		plan:        make([]string, 0),
+		releases:    make(map[int64]*gitea.Release),
+		attachments: make(map[int64]string),
+	}
+}
+
+// DryRun replaces the API client by a DryRunClient and returns it.
+func (c *Client) DryRun() *DryRunClient {
+	dryRun := NewDryRunClient(c.client)
+
+	c.client = dryRun
+	c.Release.client = dryRun
+
+	return dryRun
+}
+
+// Plan returns the recorded actions in the order they were requested.
+func (c *DryRunClient) Plan() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.plan...)
+}
+
+//nolint:lll
+func (c *DryRunClient) ListReleases(owner, repo string, opt gitea.ListReleasesOptions) ([]*gitea.Release, *gitea.Response, error) {
+	releases, resp, err := c.client.ListReleases(owner, repo, opt)
+	if err == nil {
+		c.track(releases...)
+	}
+
+	return releases, resp, err
+}
+
+func (c *DryRunClient) GetReleaseByTag(owner, repo, tag string) (*gitea.Release, *gitea.Response, error) {
+	release, resp, err := c.client.GetReleaseByTag(owner, repo, tag)
+	if err == nil && release != nil {
+		c.track(release)
+	}
+
+	return release, resp, err
+}
+
+//nolint:lll
+func (c *DryRunClient) CreateRelease(_, _ string, opt gitea.CreateReleaseOption) (*gitea.Release, *gitea.Response, error) {
+	// Validate the options like the SDK does to not plan a release that Gitea rejects.
+	if err := opt.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Planned releases get negative IDs to never collide with existing releases.
+	c.nextID--
+
+	release := &gitea.Release{
+		ID:           c.nextID,
+		TagName:      opt.TagName,
+		Target:       opt.Target,
+		Title:        opt.Title,
+		Note:         opt.Note,
+		IsDraft:      opt.IsDraft,
+		IsPrerelease: opt.IsPrerelease,
+	}
+
+	c.releases[release.ID] = release
+	c.record("create release: %s (draft: %t, prerelease: %t)", opt.TagName, opt.IsDraft, opt.IsPrerelease)
+
+	return release, nil, nil
+}
+
+//nolint:lll
+func (c *DryRunClient) EditRelease(_, _ string, id int64, form gitea.EditReleaseOption) (*gitea.Release, *gitea.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	release := &gitea.Release{ID: id}
+	if existing, ok := c.releases[id]; ok {
+		copied := *existing
+		release = &copied
+	}
+
+	if form.Title != "" {
+		release.Title = form.Title
+	}
+
+	if form.Note != "" {
+		release.Note = form.Note
+	}
+
+	if form.IsDraft != nil {
+		release.IsDraft = *form.IsDraft
+	}
+
+	if form.IsPrerelease != nil {
+		release.IsPrerelease = *form.IsPrerelease
+	}
+
+	c.releases[id] = release
+	c.record("edit release: %s (draft: %t, prerelease: %t)", release.TagName, release.IsDraft, release.IsPrerelease)
+
+	return release, nil, nil
+}
+
+func (c *DryRunClient) DeleteRelease(_, _ string, id int64) (*gitea.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := fmt.Sprint(id)
+	if release, ok := c.releases[id]; ok {
+		name = release.TagName
+	}
+
+	delete(c.releases, id)
+	c.record("delete release: %s", name)
+
+	return nil, nil
+}
+
+// ListReleaseAttachments lists the attachments of an existing release. Planned releases
+// do not exist on the server and have no attachments.
+//
+//nolint:lll
+func (c *DryRunClient) ListReleaseAttachments(user, repo string, release int64, opt gitea.ListReleaseAttachmentsOptions) ([]*gitea.Attachment, *gitea.Response, error) {
+	if release < 0 {
+		return []*gitea.Attachment{}, nil, nil
+	}
+
+	attachments, resp, err := c.client.ListReleaseAttachments(user, repo, release, opt)
+	if err == nil {
+		c.mu.Lock()
+		for _, attachment := range attachments {
+			c.attachments[attachment.ID] = attachment.Name
+		}
+		c.mu.Unlock()
+	}
+
+	return attachments, resp, err
+}
+
+//nolint:lll
+func (c *DryRunClient) CreateReleaseAttachment(_, _ string, _ int64, _ io.Reader, filename string) (*gitea.Attachment, *gitea.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("upload asset: %s", filename)
+
+	return &gitea.Attachment{Name: filename}, nil, nil
+}
+
+func (c *DryRunClient) DeleteReleaseAttachment(_, _ string, _, id int64) (*gitea.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name, ok := c.attachments[id]
+	if !ok {
+		name = fmt.Sprint(id)
+	}
+
+	c.record("delete asset: %s", name)
+
+	return nil, nil
+}
+
+// PlanSkip records an asset that is not uploaded, e.g. because it already exists.
+func (c *DryRunClient) PlanSkip(name, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.plan = append(c.plan, fmt.Sprintf("skip asset: %s (%s)", name, reason))
+}
+
+// PlanFail records an asset that can't be uploaded.
+func (c *DryRunClient) PlanFail(name string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.plan = append(c.plan, fmt.Sprintf("fail asset: %s (%v)", name, err))
+}
+
+func (c *DryRunClient) track(releases ...*gitea.Release) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, release := range releases {
+		c.releases[release.ID] = release
+	}
+}
+
+// record adds an action to the plan. The caller must hold the lock.
+func (c *DryRunClient) record(format string, args ...any) {
+	action := fmt.Sprintf(format, args...)
+
+	log.Info().Msgf("dry run: skip %s", action)
+
+	c.plan = append(c.plan, action)
+}
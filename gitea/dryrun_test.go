@@ -0,0 +1,143 @@
+package gitea
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gitea-release/gitea/mocks"
+)
+
+func TestDryRunClient(t *testing.T) {
+	opt := ReleaseOptions{
+		Owner:          "test-owner",
+		Repo:           "test-repo",
+		Tag:            "v1.0.0",
+		Title:          "Release v1.0.0",
+		FileExists:     "overwrite",
+		UpdateExisting: "merge",
+		Prune:          true,
+	}
+
+	t.Run("create release", func(t *testing.T) {
+		mockClient := mocks.NewMockAPIClient(t)
+		dryRun := NewDryRunClient(mockClient)
+		r := &Release{client: dryRun, Opt: opt}
+		r.Opt.Atomic = true
+
+		mockClient.
+			On("GetReleaseByTag", "test-owner", "test-repo", "v1.0.0").
+			Return(nil, response(http.StatusNotFound, nil), ErrReleaseNotFound)
+
+		_, err := r.Find()
+		require.ErrorIs(t, err, ErrReleaseNotFound)
+
+		release, err := r.Create()
+		require.NoError(t, err)
+		assert.Negative(t, release.ID)
+		assert.True(t, release.IsDraft)
+
+		require.NoError(t, r.AddAttachments(release.ID, NewAssets([]string{createTempFile(t, "file1.txt")})))
+
+		_, err = r.Publish(release)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"create release: v1.0.0 (draft: true, prerelease: false)",
+			"upload asset: file1.txt",
+			"edit release: v1.0.0 (draft: false, prerelease: false)",
+		}, dryRun.Plan())
+	})
+
+	t.Run("reuse release", func(t *testing.T) {
+		mockClient := mocks.NewMockAPIClient(t)
+		dryRun := NewDryRunClient(mockClient)
+		r := &Release{client: dryRun, Opt: opt}
+
+		mockClient.
+			On("GetReleaseByTag", "test-owner", "test-repo", "v1.0.0").
+			Return(&gitea.Release{ID: 1, TagName: "v1.0.0", Title: "Old title"}, nil, nil)
+		mockClient.
+			On("ListReleaseAttachments", "test-owner", "test-repo", int64(1), mock.Anything).
+			Return([]*gitea.Attachment{
+				{ID: 10, Name: "file1.txt"},
+				{ID: 11, Name: "stale.txt"},
+			}, nil, nil)
+
+		release, err := r.Find()
+		require.NoError(t, err)
+
+		release, err = r.Update(release)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), release.ID)
+		assert.Equal(t, "Release v1.0.0", release.Title)
+
+		files := []string{createTempFile(t, "file1.txt"), createTempFile(t, "file2.txt")}
+
+		err = r.AddAttachments(release.ID, NewAssets(files))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"edit release: v1.0.0 (draft: false, prerelease: false)",
+			"delete asset: file1.txt",
+			"upload asset: file1.txt",
+			"upload asset: file2.txt",
+			"delete asset: stale.txt",
+		}, dryRun.Plan())
+	})
+
+	t.Run("skip and fail assets", func(t *testing.T) {
+		mockClient := mocks.NewMockAPIClient(t)
+		dryRun := NewDryRunClient(mockClient)
+		r := &Release{client: dryRun, Opt: opt}
+		r.Opt.FileExists = "skip"
+		r.Opt.Prune = false
+
+		mockClient.
+			On("ListReleaseAttachments", "test-owner", "test-repo", int64(1), mock.Anything).
+			Return([]*gitea.Attachment{{ID: 10, Name: "file1.txt"}}, nil, nil)
+
+		missing := filepath.Join(t.TempDir(), "missing.txt")
+		files := []string{createTempFile(t, "file1.txt"), createTempFile(t, "file2.txt"), missing}
+
+		err := r.AddAttachments(1, NewAssets(files))
+		require.Error(t, err)
+
+		plan := dryRun.Plan()
+		require.Len(t, plan, 3)
+		assert.Equal(t, "skip asset: file1.txt (exists)", plan[0])
+		assert.Equal(t, "upload asset: file2.txt", plan[1])
+		assert.Regexp(t, `^fail asset: missing\.txt \(failed to read artifact: .*\)$`, plan[2])
+	})
+
+	t.Run("fail existing assets", func(t *testing.T) {
+		mockClient := mocks.NewMockAPIClient(t)
+		dryRun := NewDryRunClient(mockClient)
+		r := &Release{client: dryRun, Opt: opt}
+		r.Opt.FileExists = "fail"
+
+		mockClient.
+			On("ListReleaseAttachments", "test-owner", "test-repo", int64(1), mock.Anything).
+			Return([]*gitea.Attachment{{ID: 10, Name: "file1.txt"}}, nil, nil)
+
+		err := r.AddAttachments(1, NewAssets([]string{createTempFile(t, "file1.txt")}))
+		require.ErrorIs(t, err, ErrFileExists)
+
+		assert.Equal(t, []string{"fail asset: file1.txt (asset file already exist)"}, dryRun.Plan())
+	})
+
+	t.Run("reject empty title", func(t *testing.T) {
+		mockClient := mocks.NewMockAPIClient(t)
+		dryRun := NewDryRunClient(mockClient)
+		r := &Release{client: dryRun, Opt: opt}
+		r.Opt.Title = ""
+
+		_, err := r.Create()
+		require.ErrorContains(t, err, "title is empty")
+		assert.Empty(t, dryRun.Plan())
+	})
+}
@@ -1,11 +1,16 @@
 package gitea
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"code.gitea.io/sdk/gitea"
 	"github.com/rs/zerolog/log"
@@ -14,66 +19,210 @@ import (
 var (
 	ErrReleaseNotFound = errors.New("release not found")
 	ErrFileExists      = errors.New("asset file already exist")
+	ErrDownloadFailed  = errors.New("failed to download attachment")
+	ErrAssetNameExists = errors.New("duplicate asset name")
 )
 
+// ListPageSize is the number of items requested per page from paginated endpoints.
+const ListPageSize = 50
+
 const (
 	FileExistsOverwrite FileExists = "overwrite"
 	FileExistsFail      FileExists = "fail"
 	FileExistsSkip      FileExists = "skip"
+
+	FileExistsReplaceIfChanged FileExists = "replace-if-changed"
 )
 
+const (
+	UpdateExistingOff     UpdateExisting = "off"
+	UpdateExistingMerge   UpdateExisting = "merge"
+	UpdateExistingReplace UpdateExisting = "replace"
+)
+
+// ClearedValue replaces empty titles and notes in "replace" mode. The Gitea API ignores
+// empty strings when editing a release, so a field can't be set to an empty value.
+const ClearedValue = " "
+
+// Asset is a local file that is uploaded as release attachment with the given name.
+type Asset struct {
+	Path string
+	Name string
+}
+
 type Client struct {
 	client  APIClient
 	Release *Release
 }
 
 type Release struct {
-	client APIClient
-	Opt    ReleaseOptions
+	client   APIClient
+	download func(url string) (io.ReadCloser, error)
+	Opt      ReleaseOptions
 }
 
 type ReleaseOptions struct {
-	Owner      string
-	Repo       string
-	Tag        string
-	Draft      bool
-	Prerelease bool
-	FileExists string
-	Title      string
-	Note       string
+	Owner          string
+	Repo           string
+	Tag            string
+	Target         string
+	Draft          bool
+	Prerelease     bool
+	Atomic         bool
+	FileExists     string
+	UpdateExisting string
+	// UploadConcurrency is the maximum number of parallel uploads.
+	UploadConcurrency int
+	Title             string
+	Note              string
+	// Prune deletes existing attachments that are not part of the uploaded files.
+	Prune bool
+	// PruneKeep is a list of glob patterns for attachment names that are never pruned.
+	PruneKeep []string
+	// PruneDryRun only logs the attachments that would be pruned.
+	PruneDryRun bool
+	// Checksum calculates the checksum used to compare local files with existing attachments.
+	Checksum func(r io.Reader) (string, error)
 }
 
-type FileExists string
+type (
+	FileExists     string
+	UpdateExisting string
+)
 
 // NewClient creates a new Client instance with the provided Gitea client.
-func NewClient(url, key string, client *http.Client) (*Client, error) {
-	c, err := gitea.NewClient(url, gitea.SetToken(key), gitea.SetHTTPClient(client))
+// If retry.MaxAttempts is greater than one, transient API errors are retried.
+func NewClient(url, key string, client *http.Client, retry RetryOptions) (*Client, error) {
+	sdk, err := gitea.NewClient(url, gitea.SetToken(key), gitea.SetHTTPClient(client))
 	if err != nil {
 		return nil, err
 	}
 
+	var c APIClient = sdk
+
+	if retry.MaxAttempts > 1 {
+		c = NewRetryClient(sdk, retry)
+	}
+
 	return &Client{
 		client: c,
 		Release: &Release{
-			client: c,
-			Opt:    ReleaseOptions{},
+			client:   c,
+			download: newDownloader(client, key),
+			Opt:      ReleaseOptions{},
 		},
 	}, nil
 }
 
+// newDownloader returns a function that downloads the content of the given URL
+// authenticated with the API key.
+func newDownloader(client *http.Client, key string) func(url string) (io.ReadCloser, error) {
+	return func(url string) (io.ReadCloser, error) {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Authorization", "token "+key)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+
+			return nil, fmt.Errorf("%w: %s: %s", ErrDownloadFailed, url, resp.Status)
+		}
+
+		return resp.Body, nil
+	}
+}
+
+// NewAssets creates assets for the given files named by their base name.
+func NewAssets(files []string) []Asset {
+	assets := make([]Asset, 0, len(files))
+
+	for _, file := range files {
+		assets = append(assets, Asset{Path: file, Name: path.Base(file)})
+	}
+
+	return assets
+}
+
+// CheckAssetNames returns an error that lists all asset names used by more than one file.
+func CheckAssetNames(assets []Asset) error {
+	paths := make(map[string][]string)
+	names := make([]string, 0)
+
+	for _, asset := range assets {
+		if _, ok := paths[asset.Name]; !ok {
+			names = append(names, asset.Name)
+		}
+
+		paths[asset.Name] = append(paths[asset.Name], asset.Path)
+	}
+
+	conflicts := make([]error, 0)
+
+	for _, name := range names {
+		if len(paths[name]) > 1 {
+			conflicts = append(conflicts, fmt.Errorf("%w: %s: %s", ErrAssetNameExists, name, strings.Join(paths[name], ", ")))
+		}
+	}
+
+	return errors.Join(conflicts...)
+}
+
 // Find retrieves the release with the specified tag name from the repository.
+// It uses the get-release-by-tag endpoint and falls back to a paginated scan of
+// all releases if the lookup fails for reasons other than a missing release.
 // If the release is not found, it returns an ErrReleaseNotFound error.
 func (r *Release) Find() (*gitea.Release, error) {
-	releases, _, err := r.client.ListReleases(r.Opt.Owner, r.Opt.Repo, gitea.ListReleasesOptions{})
-	if err != nil {
-		return nil, err
+	release, resp, err := r.client.GetReleaseByTag(r.Opt.Owner, r.Opt.Repo, r.Opt.Tag)
+	if err == nil && release != nil {
+		log.Info().Msgf("found release: %s", r.Opt.Tag)
+
+		return release, nil
 	}
 
-	for _, release := range releases {
-		if release.TagName == r.Opt.Tag {
-			log.Info().Msgf("found release: %s", r.Opt.Tag)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrReleaseNotFound, r.Opt.Tag)
+	}
+
+	log.Debug().Err(err).Msgf("failed to get release by tag, fallback to list releases: %s", r.Opt.Tag)
 
-			return release, nil
+	return r.findByList()
+}
+
+// findByList scans all pages of releases of the repository for the release
+// with the specified tag name.
+func (r *Release) findByList() (*gitea.Release, error) {
+	opts := gitea.ListReleasesOptions{
+		ListOptions: gitea.ListOptions{
+			PageSize: ListPageSize,
+		},
+	}
+
+	for page := 1; ; page++ {
+		opts.Page = page
+
+		releases, resp, err := r.client.ListReleases(r.Opt.Owner, r.Opt.Repo, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, release := range releases {
+			if release.TagName == r.Opt.Tag {
+				log.Info().Msgf("found release: %s", r.Opt.Tag)
+
+				return release, nil
+			}
+		}
+
+		if len(releases) == 0 || (resp != nil && resp.LastPage > 0 && page >= resp.LastPage) {
+			break
 		}
 	}
 
@@ -81,11 +230,15 @@ func (r *Release) Find() (*gitea.Release, error) {
 }
 
 // Create creates a new release on the Gitea repository with the specified options.
+// If the tag does not exist yet, Gitea creates it from the Target commit or branch.
+// If the Atomic option is set, the release is always created as draft and has to be
+// published after all attachments are uploaded.
 // It returns the created release or an error if the creation failed.
 func (r *Release) Create() (*gitea.Release, error) {
 	opts := gitea.CreateReleaseOption{
 		TagName:      r.Opt.Tag,
-		IsDraft:      r.Opt.Draft,
+		Target:       r.Opt.Target,
+		IsDraft:      r.Opt.Draft || r.Opt.Atomic,
 		IsPrerelease: r.Opt.Prerelease,
 		Title:        r.Opt.Title,
 		Note:         r.Opt.Note,
@@ -101,16 +254,135 @@ func (r *Release) Create() (*gitea.Release, error) {
 	return release, nil
 }
 
-// AddAttachments uploads the specified files as attachments to the release with the given ID.
-// It first checks for any existing attachments with the same names,
+// Update updates the metadata of an existing release according to the UpdateExisting option:
+//
+// - "off": leaves the release untouched
+// - "merge": updates title and note only if a non-empty value is configured
+// - "replace": updates title and note to the configured values, empty values are
+// replaced by ClearedValue because Gitea ignores empty strings
+//
+// Draft and prerelease flags are synced in both "merge" and "replace" mode. In atomic mode,
+// draft releases are kept as draft until they are published.
+// Only fields that differ from the fetched release are sent to the API.
+func (r *Release) Update(release *gitea.Release) (*gitea.Release, error) {
+	mode := UpdateExisting(r.Opt.UpdateExisting)
+	if mode == "" || mode == UpdateExistingOff {
+		return release, nil
+	}
+
+	opts := gitea.EditReleaseOption{}
+	changes := make([]string, 0)
+
+	if title, ok := updateField(release.Title, r.Opt.Title, mode); ok {
+		opts.Title = title
+		changes = append(changes, fmt.Sprintf("title: %q -> %q", release.Title, title))
+	}
+
+	if note, ok := updateField(release.Note, r.Opt.Note, mode); ok {
+		opts.Note = note
+		changes = append(changes, fmt.Sprintf("note: %d -> %d bytes", len(release.Note), len(note)))
+	}
+
+	// In atomic mode, a draft release left by a failed run is published by Publish after the uploads.
+	if r.Opt.Draft != release.IsDraft && (!r.Opt.Atomic || !release.IsDraft) {
+		opts.IsDraft = &r.Opt.Draft
+		changes = append(changes, fmt.Sprintf("draft: %t -> %t", release.IsDraft, r.Opt.Draft))
+	}
+
+	if r.Opt.Prerelease != release.IsPrerelease {
+		opts.IsPrerelease = &r.Opt.Prerelease
+		changes = append(changes, fmt.Sprintf("prerelease: %t -> %t", release.IsPrerelease, r.Opt.Prerelease))
+	}
+
+	if len(changes) == 0 {
+		log.Info().Msgf("release is up to date: %s", r.Opt.Tag)
+
+		return release, nil
+	}
+
+	for _, change := range changes {
+		log.Info().Msgf("update release %s: %s", r.Opt.Tag, change)
+	}
+
+	updated, _, err := r.client.EditRelease(r.Opt.Owner, r.Opt.Repo, release.ID, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update release: %w", err)
+	}
+
+	log.Info().Msgf("updated release: %s", r.Opt.Tag)
+
+	return updated, nil
+}
+
+// updateField returns the value to send for a title or note and whether it differs from
+// the current value. Empty values are only sent in "replace" mode as ClearedValue, a
+// blank current value is considered as already cleared.
+func updateField(current, value string, mode UpdateExisting) (string, bool) {
+	if value != "" {
+		return value, value != current
+	}
+
+	if mode != UpdateExistingReplace || strings.TrimSpace(current) == "" {
+		return "", false
+	}
+
+	return ClearedValue, true
+}
+
+// Publish sets the draft state of a release in atomic mode to the configured Draft option.
+// It is a no-op if the release already has the configured draft state.
+func (r *Release) Publish(release *gitea.Release) (*gitea.Release, error) {
+	if release.IsDraft == r.Opt.Draft {
+		return release, nil
+	}
+
+	opts := gitea.EditReleaseOption{
+		IsDraft: &r.Opt.Draft,
+	}
+
+	published, _, err := r.client.EditRelease(r.Opt.Owner, r.Opt.Repo, release.ID, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish release: %w", err)
+	}
+
+	log.Info().Msgf("published release: %s", r.Opt.Tag)
+
+	return published, nil
+}
+
+// Delete deletes the given release from the Gitea repository.
+// It is used to clean up incomplete draft releases created in atomic mode.
+func (r *Release) Delete(release *gitea.Release) error {
+	if _, err := r.client.DeleteRelease(r.Opt.Owner, r.Opt.Repo, release.ID); err != nil {
+		return fmt.Errorf("failed to delete release: %w", err)
+	}
+
+	log.Info().Msgf("deleted release: %s", r.Opt.Tag)
+
+	return nil
+}
+
+// AddAttachments uploads the specified assets as attachments to the release with the given ID.
+// It fails if the asset names are not unique. Otherwise, it checks for any existing attachments
+// with the same names,
 // and handles them according to the FileExists option:
 //
 // - "overwrite": overwrites the existing attachment
+// - "replace-if-changed": overwrites the existing attachment only if the content differs
 // - "fail": returns an error if the file already exists
 // - "skip": skips uploading the file and logs a warning
 //
-// If there are no conflicts, it uploads the new files as attachments to the release.
-func (r *Release) AddAttachments(releaseID int64, files []string) error {
+// Conflicts are resolved for all files before the first upload starts. If there are no
+// conflicts, the files are uploaded by up to UploadConcurrency workers. Upload errors
+// do not stop other uploads and are returned together once all uploads are done.
+//
+// If the Prune option is set and all uploads succeeded, existing attachments that are
+// not part of the files are deleted afterwards.
+func (r *Release) AddAttachments(releaseID int64, assets []Asset) error {
+	if err := CheckAssetNames(assets); err != nil {
+		return err
+	}
+
 	attachments, _, err := r.client.ListReleaseAttachments(
 		r.Opt.Owner,
 		r.Opt.Repo,
@@ -121,55 +393,274 @@ func (r *Release) AddAttachments(releaseID int64, files []string) error {
 		return fmt.Errorf("failed to fetch attachments: %w", err)
 	}
 
-	existingAttachments := make(map[string]bool)
-	attachmentsMap := make(map[string]*gitea.Attachment)
+	existing := make(map[string]*gitea.Attachment)
 
 	for _, attachment := range attachments {
-		attachmentsMap[attachment.Name] = attachment
-		existingAttachments[attachment.Name] = true
+		existing[attachment.Name] = attachment
 	}
 
-	for _, file := range files {
-		fileName := path.Base(file)
-		if existingAttachments[fileName] {
-			switch FileExists(r.Opt.FileExists) {
-			case FileExistsOverwrite:
-				_, err := r.client.DeleteReleaseAttachment(r.Opt.Owner, r.Opt.Repo, releaseID, attachmentsMap[fileName].ID)
-				if err != nil {
-					return fmt.Errorf("failed to delete artifact: %s: %w", fileName, err)
-				}
-
-				log.Info().Msgf("deleted artifact: %s", fileName)
-			case FileExistsFail:
-				return fmt.Errorf("%w: %s", ErrFileExists, fileName)
-			case FileExistsSkip:
-				log.Warn().Msgf("skip existing artifact: %s", fileName)
-
-				continue
-			}
+	uploads := make([]upload, 0, len(assets))
+	conflicts := make([]error, 0)
+	skipped := 0
+
+	for _, asset := range assets {
+		file, fileName := asset.Path, asset.Name
+
+		attachment, ok := existing[fileName]
+		if !ok {
+			uploads = append(uploads, upload{file: file, name: fileName})
+
+			continue
+		}
+
+		switch FileExists(r.Opt.FileExists) {
+		case FileExistsOverwrite:
+			uploads = append(uploads, upload{file: file, name: fileName, replace: attachment})
+		case FileExistsReplaceIfChanged:
+			uploads = append(uploads, upload{file: file, name: fileName, replace: attachment, compare: true})
+		case FileExistsFail:
+			conflicts = append(conflicts, fmt.Errorf("%w: %s", ErrFileExists, fileName))
+			r.planFail(fileName, ErrFileExists)
+		case FileExistsSkip:
+			log.Warn().Msgf("skip existing artifact: %s", fileName)
+			r.planSkip(fileName, "exists")
+
+			skipped++
 		}
+	}
+
+	if len(conflicts) > 0 {
+		return errors.Join(conflicts...)
+	}
+
+	errs, unchanged := r.uploadFiles(releaseID, uploads)
 
-		if err := r.uploadFile(releaseID, file); err != nil {
-			return err
+	failed := 0
+
+	for _, err := range errs {
+		if err != nil {
+			failed++
 		}
 	}
 
+	log.Info().Msgf(
+		"uploaded %d artifacts, skipped %d, failed %d",
+		len(uploads)-failed-unchanged, skipped+unchanged, failed,
+	)
+
+	if failed > 0 {
+		return errors.Join(errs...)
+	}
+
+	if r.Opt.Prune {
+		return r.prune(releaseID, attachments, assets)
+	}
+
 	return nil
 }
 
-func (r *Release) uploadFile(releaseID int64, file string) error {
-	handle, err := os.Open(file)
+// prune deletes all attachments of the release that are not part of the given assets
+// and do not match any of the PruneKeep patterns. If PruneDryRun is set, the stale
+// attachments are only logged. Nothing is pruned if the assets are empty.
+func (r *Release) prune(releaseID int64, attachments []*gitea.Attachment, assets []Asset) error {
+	// An empty upload set is most likely caused by a file pattern that matched nothing,
+	// pruning would delete every attachment of the release.
+	if len(assets) == 0 {
+		log.Warn().Msg("skip pruning, no files to upload")
+
+		return nil
+	}
+
+	names := make(map[string]bool, len(assets))
+
+	for _, asset := range assets {
+		names[asset.Name] = true
+	}
+
+	errs := make([]error, 0)
+	pruned := 0
+
+	for _, attachment := range attachments {
+		if names[attachment.Name] || r.keep(attachment.Name) {
+			continue
+		}
+
+		if r.Opt.PruneDryRun {
+			log.Info().Msgf("would prune artifact: %s", attachment.Name)
+
+			continue
+		}
+
+		_, err := r.client.DeleteReleaseAttachment(r.Opt.Owner, r.Opt.Repo, releaseID, attachment.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to prune artifact: %s: %w", attachment.Name, err))
+
+			continue
+		}
+
+		log.Info().Msgf("pruned artifact: %s", attachment.Name)
+
+		pruned++
+	}
+
+	if !r.Opt.PruneDryRun {
+		log.Info().Msgf("pruned %d artifacts", pruned)
+	}
+
+	return errors.Join(errs...)
+}
+
+// keep reports whether the attachment name matches any of the PruneKeep patterns.
+func (r *Release) keep(name string) bool {
+	for _, pattern := range r.Opt.PruneKeep {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// planner is implemented by API clients that record the planned actions instead of
+// applying them. Skipped and failed assets never reach the API, so they are reported
+// to the planner by the release.
+type planner interface {
+	PlanSkip(name, reason string)
+	PlanFail(name string, err error)
+}
+
+// planSkip reports a skipped asset to the API client if it records a plan.
+func (r *Release) planSkip(name, reason string) {
+	if p, ok := r.client.(planner); ok {
+		p.PlanSkip(name, reason)
+	}
+}
+
+// planFail reports a failed asset to the API client if it records a plan.
+func (r *Release) planFail(name string, err error) {
+	if p, ok := r.client.(planner); ok {
+		p.PlanFail(name, err)
+	}
+}
+
+// upload is a planned upload of a file as release attachment.
+type upload struct {
+	file    string
+	name    string
+	replace *gitea.Attachment
+	// compare only replaces the existing attachment if the content has changed.
+	compare bool
+}
+
+// uploadFiles runs the given uploads with a bounded number of workers. It returns the
+// upload errors in the order of the given uploads and the number of unchanged files.
+func (r *Release) uploadFiles(releaseID int64, uploads []upload) ([]error, int) {
+	errs := make([]error, len(uploads))
+	sem := make(chan struct{}, max(r.Opt.UploadConcurrency, 1))
+
+	var (
+		wg        sync.WaitGroup
+		unchanged atomic.Int64
+	)
+
+	for i, u := range uploads {
+		sem <- struct{}{}
+
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			uploaded, err := r.uploadFile(releaseID, u)
+
+			switch {
+			case err != nil:
+				r.planFail(u.name, err)
+			case !uploaded:
+				r.planSkip(u.name, "unchanged")
+				unchanged.Add(1)
+			}
+
+			errs[i] = err
+		})
+	}
+
+	wg.Wait()
+
+	return errs, int(unchanged.Load())
+}
+
+// uploadFile uploads a single file and returns false if the upload was skipped
+// because the existing attachment has the same content.
+func (r *Release) uploadFile(releaseID int64, u upload) (bool, error) {
+	handle, err := os.Open(u.file)
 	if err != nil {
-		return fmt.Errorf("failed to read artifact: %s: %w", file, err)
+		return false, fmt.Errorf("failed to read artifact: %s: %w", u.file, err)
 	}
 	defer handle.Close()
 
-	_, _, err = r.client.CreateReleaseAttachment(r.Opt.Owner, r.Opt.Repo, releaseID, handle, path.Base(file))
+	if u.compare {
+		changed, err := r.changed(handle, u.replace)
+		if err != nil {
+			return false, fmt.Errorf("failed to compare artifact: %s: %w", u.name, err)
+		}
+
+		if !changed {
+			log.Info().Msgf("skip unchanged artifact: %s", u.name)
+
+			return false, nil
+		}
+
+		if _, err := handle.Seek(0, io.SeekStart); err != nil {
+			return false, fmt.Errorf("failed to read artifact: %s: %w", u.file, err)
+		}
+	}
+
+	if u.replace != nil {
+		_, err := r.client.DeleteReleaseAttachment(r.Opt.Owner, r.Opt.Repo, releaseID, u.replace.ID)
+		if err != nil {
+			return false, fmt.Errorf("failed to delete artifact: %s: %w", u.name, err)
+		}
+
+		log.Info().Msgf("deleted artifact: %s", u.name)
+	}
+
+	_, _, err = r.client.CreateReleaseAttachment(r.Opt.Owner, r.Opt.Repo, releaseID, handle, u.name)
 	if err != nil {
-		return fmt.Errorf("failed to upload artifact: %s: %w", file, err)
+		return false, fmt.Errorf("failed to upload artifact: %s: %w", u.file, err)
 	}
 
-	log.Info().Msgf("uploaded artifact: %s", path.Base(file))
+	log.Info().Msgf("uploaded artifact: %s", u.name)
 
-	return nil
+	return true, nil
+}
+
+// changed reports whether the content of the local file differs from the existing attachment.
+// Files with a different size are changed. Otherwise, the attachment is downloaded and the
+// checksums of both are compared. Without a Checksum function, files are always changed.
+func (r *Release) changed(file *os.File, attachment *gitea.Attachment) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if info.Size() != attachment.Size || r.Opt.Checksum == nil || r.download == nil {
+		return true, nil
+	}
+
+	local, err := r.Opt.Checksum(file)
+	if err != nil {
+		return false, err
+	}
+
+	body, err := r.download(attachment.DownloadURL)
+	if err != nil {
+		return false, err
+	}
+	defer body.Close()
+
+	remote, err := r.Opt.Checksum(body)
+	if err != nil {
+		return false, err
+	}
+
+	return local != remote, nil
 }
@@ -2,9 +2,15 @@ package gitea
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"code.gitea.io/sdk/gitea"
@@ -15,14 +21,22 @@ import (
 	"github.com/thegeeklab/wp-gitea-release/gitea/mocks"
 )
 
-var ErrNoSuchFileOrDirectory = errors.New("no such file or directory")
+var (
+	ErrNoSuchFileOrDirectory = errors.New("no such file or directory")
+	ErrUnknownVersion        = errors.New("unknown version")
+	ErrUploadFailed          = errors.New("upload failed")
+)
 
 func TestReleaseFind(t *testing.T) {
 	tests := []struct {
-		name    string
-		opt     ReleaseOptions
-		want    *gitea.Release
-		wantErr error
+		name      string
+		opt       ReleaseOptions
+		byTag     *gitea.Release
+		byTagResp *gitea.Response
+		byTagErr  error
+		pages     [][]*gitea.Release
+		want      *gitea.Release
+		wantErr   error
 	}{
 		{
 			name: "find release by tag",
@@ -31,6 +45,10 @@ func TestReleaseFind(t *testing.T) {
 				Repo:  "test-repo",
 				Tag:   "v1.0.0",
 			},
+			byTag: &gitea.Release{
+				ID:      1,
+				TagName: "v1.0.0",
+			},
 			want: &gitea.Release{
 				TagName: "v1.0.0",
 			},
@@ -42,6 +60,40 @@ func TestReleaseFind(t *testing.T) {
 				Repo:  "test-repo",
 				Tag:   "v1.1.0",
 			},
+			byTagResp: &gitea.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+			byTagErr:  ErrReleaseNotFound,
+			want:      nil,
+			wantErr:   ErrReleaseNotFound,
+		},
+		{
+			name: "find release on later page",
+			opt: ReleaseOptions{
+				Owner: "test-owner",
+				Repo:  "test-repo",
+				Tag:   "v0.1.0",
+			},
+			byTagErr: ErrUnknownVersion,
+			pages: [][]*gitea.Release{
+				releasePage(1, ListPageSize),
+				releasePage(ListPageSize+1, ListPageSize),
+				{{ID: 200, TagName: "v0.2.0"}, {ID: 201, TagName: "v0.1.0"}},
+			},
+			want: &gitea.Release{
+				TagName: "v0.1.0",
+			},
+		},
+		{
+			name: "release not found on any page",
+			opt: ReleaseOptions{
+				Owner: "test-owner",
+				Repo:  "test-repo",
+				Tag:   "v0.0.1",
+			},
+			byTagErr: ErrUnknownVersion,
+			pages: [][]*gitea.Release{
+				releasePage(1, ListPageSize),
+				releasePage(ListPageSize+1, 10),
+			},
 			want:    nil,
 			wantErr: ErrReleaseNotFound,
 		},
@@ -55,23 +107,20 @@ func TestReleaseFind(t *testing.T) {
 		}
 
 		mockClient.
-			On("ListReleases", mock.Anything, mock.Anything, mock.Anything).
-			Return([]*gitea.Release{
-				{
-					ID:           1,
-					TagName:      "v1.0.0",
-					Title:        "Release v1.0.0",
-					Note:         "This is the release notes for v1.0.0",
-					IsDraft:      false,
-					IsPrerelease: false,
-				},
-			}, nil, nil)
+			On("GetReleaseByTag", tt.opt.Owner, tt.opt.Repo, tt.opt.Tag).
+			Return(tt.byTag, tt.byTagResp, tt.byTagErr)
+
+		if tt.pages != nil {
+			mockClient.
+				On("ListReleases", tt.opt.Owner, tt.opt.Repo, mock.Anything).
+				Return(fakeListReleases(tt.pages))
+		}
 
 		t.Run(tt.name, func(t *testing.T) {
 			release, err := r.Find()
 
 			if tt.wantErr != nil {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, tt.wantErr)
 				assert.Nil(t, release)
 
 				return
@@ -147,6 +196,41 @@ func TestReleaseCreate(t *testing.T) {
 				IsPrerelease: true,
 			},
 		},
+		{
+			name: "create release with target",
+			opt: ReleaseOptions{
+				Owner:  "test-owner",
+				Repo:   "test-repo",
+				Tag:    "v1.3.1",
+				Target: "3d4e5f6",
+				Title:  "Release v1.3.1",
+			},
+			want: &gitea.Release{
+				TagName: "v1.3.1",
+				Target:  "3d4e5f6",
+				Title:   "Release v1.3.1",
+			},
+		},
+		{
+			name: "create atomic release as draft",
+			opt: ReleaseOptions{
+				Owner:      "test-owner",
+				Repo:       "test-repo",
+				Tag:        "v1.4.0",
+				Title:      "Release v1.4.0",
+				Note:       "This is the release notes for v1.4.0",
+				Draft:      false,
+				Prerelease: false,
+				Atomic:     true,
+			},
+			want: &gitea.Release{
+				TagName:      "v1.4.0",
+				Title:        "Release v1.4.0",
+				Note:         "This is the release notes for v1.4.0",
+				IsDraft:      true,
+				IsPrerelease: false,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -157,13 +241,16 @@ func TestReleaseCreate(t *testing.T) {
 		}
 
 		mockClient.
-			On("CreateRelease", mock.Anything, mock.Anything, mock.Anything).
+			On("CreateRelease", mock.Anything, mock.Anything, mock.MatchedBy(func(opt gitea.CreateReleaseOption) bool {
+				return opt.IsDraft == tt.want.IsDraft && opt.Target == tt.want.Target
+			})).
 			Return(&gitea.Release{
 				ID:           1,
 				TagName:      tt.opt.Tag,
+				Target:       tt.opt.Target,
 				Title:        tt.opt.Title,
 				Note:         tt.opt.Note,
-				IsDraft:      tt.opt.Draft,
+				IsDraft:      tt.want.IsDraft,
 				IsPrerelease: tt.opt.Prerelease,
 			}, nil, nil)
 
@@ -179,6 +266,7 @@ func TestReleaseCreate(t *testing.T) {
 
 			assert.NoError(t, err)
 			assert.Equal(t, tt.want.TagName, release.TagName)
+			assert.Equal(t, tt.want.Target, release.Target)
 			assert.Equal(t, tt.want.Title, release.Title)
 			assert.Equal(t, tt.want.Note, release.Note)
 			assert.Equal(t, tt.want.IsDraft, release.IsDraft)
@@ -187,18 +275,222 @@ func TestReleaseCreate(t *testing.T) {
 	}
 }
 
-func TestReleaseAddAttachments(t *testing.T) {
+func TestReleaseUpdate(t *testing.T) {
 	logBuffer := &bytes.Buffer{}
 	logger := zerolog.New(logBuffer)
 	log.Logger = logger
 
+	existing := &gitea.Release{
+		ID:           1,
+		TagName:      "v1.0.0",
+		Title:        "Release v1.0.0",
+		Note:         "Old notes",
+		IsDraft:      false,
+		IsPrerelease: false,
+	}
+
+	tests := []struct {
+		name     string
+		opt      ReleaseOptions
+		release  *gitea.Release
+		wantEdit *gitea.EditReleaseOption
+		wantLogs []string
+	}{
+		{
+			name: "update disabled",
+			opt: ReleaseOptions{
+				Tag:            "v1.0.0",
+				Title:          "New title",
+				UpdateExisting: "off",
+			},
+		},
+		{
+			name: "release up to date",
+			opt: ReleaseOptions{
+				Tag:            "v1.0.0",
+				Title:          "Release v1.0.0",
+				Note:           "Old notes",
+				UpdateExisting: "merge",
+			},
+			wantLogs: []string{"release is up to date: v1.0.0"},
+		},
+		{
+			name: "merge keeps empty fields",
+			opt: ReleaseOptions{
+				Tag:            "v1.0.0",
+				Title:          "New title",
+				Prerelease:     true,
+				UpdateExisting: "merge",
+			},
+			wantEdit: &gitea.EditReleaseOption{
+				Title:        "New title",
+				IsPrerelease: boolPtr(true),
+			},
+			wantLogs: []string{
+				`update release v1.0.0: title: \"Release v1.0.0\" -> \"New title\"`,
+				"update release v1.0.0: prerelease: false -> true",
+			},
+		},
+		{
+			name: "replace all fields",
+			opt: ReleaseOptions{
+				Tag:            "v1.0.0",
+				Title:          "Release v1.0.0",
+				Note:           "",
+				Draft:          true,
+				UpdateExisting: "replace",
+			},
+			wantEdit: &gitea.EditReleaseOption{
+				Note:    ClearedValue,
+				IsDraft: boolPtr(true),
+			},
+			wantLogs: []string{
+				"update release v1.0.0: note: 9 -> 1 bytes",
+				"update release v1.0.0: draft: false -> true",
+			},
+		},
+		{
+			name: "atomic keeps draft",
+			opt: ReleaseOptions{
+				Tag:            "v1.0.0",
+				Title:          "Release v1.0.0",
+				Note:           "Old notes",
+				Atomic:         true,
+				UpdateExisting: "merge",
+			},
+			release:  &gitea.Release{ID: 1, TagName: "v1.0.0", Title: "Release v1.0.0", Note: "Old notes", IsDraft: true},
+			wantLogs: []string{"release is up to date: v1.0.0"},
+		},
+		{
+			name: "replace keeps cleared fields",
+			opt: ReleaseOptions{
+				Tag:            "v1.0.0",
+				Title:          "Release v1.0.0",
+				UpdateExisting: "replace",
+			},
+			release:  &gitea.Release{ID: 1, TagName: "v1.0.0", Title: "Release v1.0.0", Note: ClearedValue},
+			wantLogs: []string{"release is up to date: v1.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		logBuffer.Reset()
+
+		mockClient := mocks.NewMockAPIClient(t)
+		r := &Release{
+			Opt:    tt.opt,
+			client: mockClient,
+		}
+
+		if tt.wantEdit != nil {
+			mockClient.
+				On("EditRelease", mock.Anything, mock.Anything, existing.ID, *tt.wantEdit).
+				Return(&gitea.Release{ID: existing.ID, TagName: existing.TagName}, nil, nil)
+		}
+
+		current := existing
+		if tt.release != nil {
+			current = tt.release
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			release, err := r.Update(current)
+
+			for _, l := range tt.wantLogs {
+				assert.Contains(t, logBuffer.String(), l)
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, existing.ID, release.ID)
+		})
+	}
+}
+
+func TestReleasePublish(t *testing.T) {
+	tests := []struct {
+		name        string
+		opt         ReleaseOptions
+		release     *gitea.Release
+		wantEdit    bool
+		wantIsDraft bool
+	}{
+		{
+			name: "publish draft release",
+			opt: ReleaseOptions{
+				Tag:    "v1.0.0",
+				Draft:  false,
+				Atomic: true,
+			},
+			release:     &gitea.Release{ID: 1, TagName: "v1.0.0", IsDraft: true},
+			wantEdit:    true,
+			wantIsDraft: false,
+		},
+		{
+			name: "keep draft release",
+			opt: ReleaseOptions{
+				Tag:    "v1.0.0",
+				Draft:  true,
+				Atomic: true,
+			},
+			release:     &gitea.Release{ID: 1, TagName: "v1.0.0", IsDraft: true},
+			wantEdit:    false,
+			wantIsDraft: true,
+		},
+	}
+
+	for _, tt := range tests {
+		mockClient := mocks.NewMockAPIClient(t)
+		r := &Release{
+			Opt:    tt.opt,
+			client: mockClient,
+		}
+
+		if tt.wantEdit {
+			mockClient.
+				On("EditRelease", mock.Anything, mock.Anything, tt.release.ID, gitea.EditReleaseOption{
+					IsDraft: boolPtr(tt.wantIsDraft),
+				}).
+				Return(&gitea.Release{ID: tt.release.ID, IsDraft: tt.wantIsDraft}, nil, nil)
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			release, err := r.Publish(tt.release)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantIsDraft, release.IsDraft)
+		})
+	}
+}
+
+func TestReleaseDelete(t *testing.T) {
+	mockClient := mocks.NewMockAPIClient(t)
+	r := &Release{
+		Opt:    ReleaseOptions{Owner: "test-owner", Repo: "test-repo", Tag: "v1.0.0"},
+		client: mockClient,
+	}
+
+	mockClient.
+		On("DeleteRelease", "test-owner", "test-repo", int64(1)).
+		Return(nil, nil)
+
+	assert.NoError(t, r.Delete(&gitea.Release{ID: 1}))
+}
+
+func TestReleaseAddAttachments(t *testing.T) {
+	logBuffer := &bytes.Buffer{}
+	logger := zerolog.New(zerolog.SyncWriter(logBuffer))
+	log.Logger = logger
+
 	tests := []struct {
-		name       string
-		opt        ReleaseOptions
-		files      []string
-		fileExists string
-		wantErr    error
-		wantLogs   []string
+		name        string
+		opt         ReleaseOptions
+		files       []string
+		uploadErr   map[string]error
+		remote      string
+		wantDeletes int
+		wantUploads []string
+		wantErr     []error
+		wantLogs    []string
 	}{
 		{
 			name: "add new attachments",
@@ -209,8 +501,23 @@ func TestReleaseAddAttachments(t *testing.T) {
 				Title:      "Release v2.0.0",
 				FileExists: "overwrite",
 			},
-			files:    []string{createTempFile(t, "file1.txt"), createTempFile(t, "file2.txt")},
-			wantLogs: []string{"uploaded artifact: file1.txt", "uploaded artifact: file2.txt"},
+			files:       []string{createTempFile(t, "file1.txt"), createTempFile(t, "file2.txt")},
+			wantDeletes: 1,
+			wantUploads: []string{"file1.txt", "file2.txt"},
+			wantLogs:    []string{"uploaded artifact: file1.txt", "uploaded artifact: file2.txt"},
+		},
+		{
+			name: "add only new attachments",
+			opt: ReleaseOptions{
+				Owner:      "test-owner",
+				Repo:       "test-repo",
+				Tag:        "v2.0.0",
+				Title:      "Release v2.0.0",
+				FileExists: "overwrite",
+			},
+			files:       []string{createTempFile(t, "file2.txt"), createTempFile(t, "file3.txt")},
+			wantUploads: []string{"file2.txt", "file3.txt"},
+			wantLogs:    []string{"uploaded artifact: file2.txt", "uploaded artifact: file3.txt"},
 		},
 		{
 			name: "fail on existing attachments",
@@ -222,7 +529,7 @@ func TestReleaseAddAttachments(t *testing.T) {
 				FileExists: "fail",
 			},
 			files:   []string{createTempFile(t, "file1.txt"), createTempFile(t, "file2.txt")},
-			wantErr: ErrFileExists,
+			wantErr: []error{ErrFileExists},
 		},
 		{
 			name: "overwrite on existing attachments",
@@ -233,8 +540,10 @@ func TestReleaseAddAttachments(t *testing.T) {
 				Title:      "Release v2.0.0",
 				FileExists: "overwrite",
 			},
-			files:    []string{createTempFile(t, "file1.txt"), createTempFile(t, "file2.txt")},
-			wantLogs: []string{"deleted artifact: file1.txt", "uploaded artifact: file1.txt"},
+			files:       []string{createTempFile(t, "file1.txt"), createTempFile(t, "file2.txt")},
+			wantDeletes: 1,
+			wantUploads: []string{"file1.txt", "file2.txt"},
+			wantLogs:    []string{"deleted artifact: file1.txt", "uploaded artifact: file1.txt"},
 		},
 		{
 			name: "skip on existing attachments",
@@ -245,8 +554,9 @@ func TestReleaseAddAttachments(t *testing.T) {
 				Title:      "Release v2.0.0",
 				FileExists: "skip",
 			},
-			files:    []string{createTempFile(t, "file1.txt"), createTempFile(t, "file2.txt")},
-			wantLogs: []string{"skip existing artifact: file1"},
+			files:       []string{createTempFile(t, "file1.txt"), createTempFile(t, "file2.txt")},
+			wantUploads: []string{"file2.txt"},
+			wantLogs:    []string{"skip existing artifact: file1", "uploaded 1 artifacts, skipped 1, failed 0"},
 		},
 		{
 			name: "fail on invalid file",
@@ -258,7 +568,105 @@ func TestReleaseAddAttachments(t *testing.T) {
 				FileExists: "overwrite",
 			},
 			files:   []string{"testdata/file1.txt", "testdata/invalid.txt"},
-			wantErr: ErrNoSuchFileOrDirectory,
+			wantErr: []error{ErrNoSuchFileOrDirectory},
+		},
+		{
+			name: "fail on duplicate asset names",
+			opt: ReleaseOptions{
+				Owner:      "test-owner",
+				Repo:       "test-repo",
+				Tag:        "v2.0.0",
+				Title:      "Release v2.0.0",
+				FileExists: "overwrite",
+			},
+			files:   []string{createTempFile(t, "file2.txt"), createTempFile(t, "file3.txt"), createTempFile(t, "file2.txt")},
+			wantErr: []error{ErrAssetNameExists},
+		},
+		{
+			name: "parallel uploads",
+			opt: ReleaseOptions{
+				Owner:             "test-owner",
+				Repo:              "test-repo",
+				Tag:               "v2.0.0",
+				Title:             "Release v2.0.0",
+				FileExists:        "overwrite",
+				UploadConcurrency: 3,
+			},
+			files: []string{
+				createTempFile(t, "file1.txt"), createTempFile(t, "file2.txt"), createTempFile(t, "file3.txt"),
+				createTempFile(t, "file4.txt"), createTempFile(t, "file5.txt"),
+			},
+			wantDeletes: 1,
+			wantUploads: []string{"file1.txt", "file2.txt", "file3.txt", "file4.txt", "file5.txt"},
+			wantLogs:    []string{"uploaded 5 artifacts, skipped 0, failed 0"},
+		},
+		{
+			name: "collect all upload errors",
+			opt: ReleaseOptions{
+				Owner:             "test-owner",
+				Repo:              "test-repo",
+				Tag:               "v2.0.0",
+				Title:             "Release v2.0.0",
+				FileExists:        "skip",
+				UploadConcurrency: 2,
+			},
+			files: []string{
+				createTempFile(t, "file2.txt"), createTempFile(t, "file3.txt"), createTempFile(t, "file4.txt"),
+			},
+			uploadErr: map[string]error{
+				"file2.txt": ErrUploadFailed,
+				"file4.txt": ErrUploadFailed,
+			},
+			wantUploads: []string{"file2.txt", "file3.txt", "file4.txt"},
+			wantErr:     []error{ErrUploadFailed},
+			wantLogs:    []string{"uploaded 1 artifacts, skipped 0, failed 2"},
+		},
+		{
+			name: "skip unchanged attachments",
+			opt: ReleaseOptions{
+				Owner:      "test-owner",
+				Repo:       "test-repo",
+				Tag:        "v2.0.0",
+				Title:      "Release v2.0.0",
+				FileExists: "replace-if-changed",
+				Checksum:   sha256Sum,
+			},
+			files:       []string{createTempFile(t, "file1.txt"), createTempFile(t, "file2.txt")},
+			remote:      "hello",
+			wantUploads: []string{"file2.txt"},
+			wantLogs:    []string{"skip unchanged artifact: file1.txt", "uploaded 1 artifacts, skipped 1, failed 0"},
+		},
+		{
+			name: "replace changed attachments",
+			opt: ReleaseOptions{
+				Owner:      "test-owner",
+				Repo:       "test-repo",
+				Tag:        "v2.0.0",
+				Title:      "Release v2.0.0",
+				FileExists: "replace-if-changed",
+				Checksum:   sha256Sum,
+			},
+			files:       []string{createTempFile(t, "file1.txt")},
+			remote:      "world",
+			wantDeletes: 1,
+			wantUploads: []string{"file1.txt"},
+			wantLogs:    []string{"deleted artifact: file1.txt", "uploaded 1 artifacts, skipped 0, failed 0"},
+		},
+		{
+			name: "replace attachments with different size",
+			opt: ReleaseOptions{
+				Owner:      "test-owner",
+				Repo:       "test-repo",
+				Tag:        "v2.0.0",
+				Title:      "Release v2.0.0",
+				FileExists: "replace-if-changed",
+				Checksum:   sha256Sum,
+			},
+			files:       []string{createTempFile(t, "file1.txt")},
+			remote:      "hello world",
+			wantDeletes: 1,
+			wantUploads: []string{"file1.txt"},
+			wantLogs:    []string{"uploaded 1 artifacts, skipped 0, failed 0"},
 		},
 	}
 
@@ -269,30 +677,38 @@ func TestReleaseAddAttachments(t *testing.T) {
 		r := &Release{
 			Opt:    tt.opt,
 			client: mockClient,
+			download: func(_ string) (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(tt.remote)), nil
+			},
 		}
 
 		mockClient.
 			On("ListReleaseAttachments", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 			Return([]*gitea.Attachment{
 				{
+					ID:   10,
 					Name: "file1.txt",
+					Size: int64(len(tt.remote)),
 				},
-			}, nil, nil)
+			}, nil, nil).
+			Maybe()
 
-		if FileExists(tt.opt.FileExists) == FileExistsOverwrite {
+		if tt.wantDeletes > 0 {
 			mockClient.
-				On("DeleteReleaseAttachment", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
-				Return(nil, nil)
+				On("DeleteReleaseAttachment", mock.Anything, mock.Anything, mock.Anything, int64(10)).
+				Return(nil, nil).
+				Times(tt.wantDeletes)
 		}
 
-		if tt.wantErr == nil {
+		for _, name := range tt.wantUploads {
 			mockClient.
-				On("CreateReleaseAttachment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
-				Return(nil, nil, nil)
+				On("CreateReleaseAttachment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, name).
+				Return(nil, nil, tt.uploadErr[name]).
+				Once()
 		}
 
 		t.Run(tt.name, func(t *testing.T) {
-			err := r.AddAttachments(1, tt.files)
+			err := r.AddAttachments(1, NewAssets(tt.files))
 
 			// Assert log output.
 			for _, l := range tt.wantLogs {
@@ -301,7 +717,16 @@ func TestReleaseAddAttachments(t *testing.T) {
 
 			if tt.wantErr != nil {
 				assert.Error(t, err)
-				assert.ErrorContains(t, err, tt.wantErr.Error())
+
+				for _, wantErr := range tt.wantErr {
+					assert.ErrorContains(t, err, wantErr.Error())
+				}
+
+				if len(tt.uploadErr) > 0 {
+					for name := range tt.uploadErr {
+						assert.ErrorContains(t, err, name)
+					}
+				}
 
 				return
 			}
@@ -311,6 +736,126 @@ func TestReleaseAddAttachments(t *testing.T) {
 	}
 }
 
+func TestReleasePrune(t *testing.T) {
+	logBuffer := &bytes.Buffer{}
+	logger := zerolog.New(zerolog.SyncWriter(logBuffer))
+	log.Logger = logger
+
+	attachments := []*gitea.Attachment{
+		{ID: 10, Name: "file1.txt"},
+		{ID: 11, Name: "app-arm.tar.gz"},
+		{ID: 12, Name: "app-mips.tar.gz"},
+		{ID: 13, Name: "install.sh"},
+	}
+
+	tests := []struct {
+		name        string
+		opt         ReleaseOptions
+		files       []string
+		uploadErr   error
+		wantDeletes []int64
+		wantLogs    []string
+		wantErr     error
+	}{
+		{
+			name:     "no prune",
+			opt:      ReleaseOptions{FileExists: "skip"},
+			wantLogs: []string{"uploaded 0 artifacts, skipped 1, failed 0"},
+		},
+		{
+			name:        "prune stale attachments",
+			opt:         ReleaseOptions{FileExists: "skip", Prune: true},
+			wantDeletes: []int64{11, 12, 13},
+			wantLogs:    []string{"pruned artifact: app-arm.tar.gz", "pruned 3 artifacts"},
+		},
+		{
+			name:        "keep matching attachments",
+			opt:         ReleaseOptions{FileExists: "skip", Prune: true, PruneKeep: []string{"*.sh", "*-arm.*"}},
+			wantDeletes: []int64{12},
+			wantLogs:    []string{"pruned artifact: app-mips.tar.gz", "pruned 1 artifacts"},
+		},
+		{
+			name:     "dry run",
+			opt:      ReleaseOptions{FileExists: "skip", Prune: true, PruneDryRun: true},
+			wantLogs: []string{"would prune artifact: app-arm.tar.gz", "would prune artifact: install.sh"},
+		},
+		{
+			name:      "no prune on upload errors",
+			opt:       ReleaseOptions{FileExists: "overwrite", Prune: true},
+			uploadErr: ErrUploadFailed,
+			wantErr:   ErrUploadFailed,
+		},
+		{
+			name:     "no prune without files",
+			opt:      ReleaseOptions{FileExists: "skip", Prune: true},
+			files:    []string{},
+			wantLogs: []string{"uploaded 0 artifacts, skipped 0, failed 0", "skip pruning, no files to upload"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logBuffer.Reset()
+
+			mockClient := mocks.NewMockAPIClient(t)
+			r := &Release{
+				Opt:    tt.opt,
+				client: mockClient,
+			}
+
+			mockClient.
+				On("ListReleaseAttachments", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(attachments, nil, nil)
+
+			if tt.uploadErr != nil {
+				mockClient.
+					On("DeleteReleaseAttachment", mock.Anything, mock.Anything, mock.Anything, int64(10)).
+					Return(nil, nil).
+					Once()
+				mockClient.
+					On("CreateReleaseAttachment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, "file1.txt").
+					Return(nil, nil, tt.uploadErr).
+					Once()
+			}
+
+			for _, id := range tt.wantDeletes {
+				mockClient.
+					On("DeleteReleaseAttachment", mock.Anything, mock.Anything, mock.Anything, id).
+					Return(nil, nil).
+					Once()
+			}
+
+			files := tt.files
+			if files == nil {
+				files = []string{createTempFile(t, "file1.txt")}
+			}
+
+			err := r.AddAttachments(1, NewAssets(files))
+
+			for _, l := range tt.wantLogs {
+				assert.Contains(t, logBuffer.String(), l)
+			}
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func sha256Sum(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func createTempFile(t *testing.T, name string) string {
 	t.Helper()
 
@@ -319,3 +864,34 @@ func createTempFile(t *testing.T, name string) string {
 
 	return name
 }
+
+// fakeListReleases returns a ListReleases implementation that serves the given pages
+// and an empty result for any page beyond.
+func fakeListReleases(
+	pages [][]*gitea.Release,
+) func(string, string, gitea.ListReleasesOptions) ([]*gitea.Release, *gitea.Response, error) {
+	return func(_, _ string, opt gitea.ListReleasesOptions) ([]*gitea.Release, *gitea.Response, error) {
+		if opt.Page < 1 || opt.Page > len(pages) {
+			return []*gitea.Release{}, nil, nil
+		}
+
+		return pages[opt.Page-1], nil, nil
+	}
+}
+
+func releasePage(start, size int) []*gitea.Release {
+	releases := make([]*gitea.Release, 0, size)
+
+	for i := start; i < start+size; i++ {
+		releases = append(releases, &gitea.Release{
+			ID:      int64(i),
+			TagName: fmt.Sprintf("v2.%d.0", i),
+		})
+	}
+
+	return releases
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -163,6 +163,66 @@ func (_c *MockAPIClient_CreateReleaseAttachment_Call) RunAndReturn(run func(stri
 	return _c
 }
 
+// DeleteRelease provides a mock function with given fields: user, repo, id
+func (_m *MockAPIClient) DeleteRelease(user string, repo string, id int64) (*gitea.Response, error) {
+	ret := _m.Called(user, repo, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRelease")
+	}
+
+	var r0 *gitea.Response
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, int64) (*gitea.Response, error)); ok {
+		return rf(user, repo, id)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, int64) *gitea.Response); ok {
+		r0 = rf(user, repo, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*gitea.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, int64) error); ok {
+		r1 = rf(user, repo, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAPIClient_DeleteRelease_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteRelease'
+type MockAPIClient_DeleteRelease_Call struct {
+	*mock.Call
+}
+
+// DeleteRelease is a helper method to define mock.On call
+//   - user string
+//   - repo string
+//   - id int64
+func (_e *MockAPIClient_Expecter) DeleteRelease(user interface{}, repo interface{}, id interface{}) *MockAPIClient_DeleteRelease_Call {
+	return &MockAPIClient_DeleteRelease_Call{Call: _e.mock.On("DeleteRelease", user, repo, id)}
+}
+
+func (_c *MockAPIClient_DeleteRelease_Call) Run(run func(user string, repo string, id int64)) *MockAPIClient_DeleteRelease_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(int64))
+	})
+	return _c
+}
+
+func (_c *MockAPIClient_DeleteRelease_Call) Return(_a0 *gitea.Response, _a1 error) *MockAPIClient_DeleteRelease_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAPIClient_DeleteRelease_Call) RunAndReturn(run func(string, string, int64) (*gitea.Response, error)) *MockAPIClient_DeleteRelease_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DeleteReleaseAttachment provides a mock function with given fields: user, repo, release, id
 func (_m *MockAPIClient) DeleteReleaseAttachment(user string, repo string, release int64, id int64) (*gitea.Response, error) {
 	ret := _m.Called(user, repo, release, id)
@@ -224,6 +284,145 @@ func (_c *MockAPIClient_DeleteReleaseAttachment_Call) RunAndReturn(run func(stri
 	return _c
 }
 
+// EditRelease provides a mock function with given fields: owner, repo, id, form
+func (_m *MockAPIClient) EditRelease(owner string, repo string, id int64, form gitea.EditReleaseOption) (*gitea.Release, *gitea.Response, error) {
+	ret := _m.Called(owner, repo, id, form)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EditRelease")
+	}
+
+	var r0 *gitea.Release
+	var r1 *gitea.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string, string, int64, gitea.EditReleaseOption) (*gitea.Release, *gitea.Response, error)); ok {
+		return rf(owner, repo, id, form)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, int64, gitea.EditReleaseOption) *gitea.Release); ok {
+		r0 = rf(owner, repo, id, form)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*gitea.Release)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, int64, gitea.EditReleaseOption) *gitea.Response); ok {
+		r1 = rf(owner, repo, id, form)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*gitea.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(string, string, int64, gitea.EditReleaseOption) error); ok {
+		r2 = rf(owner, repo, id, form)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MockAPIClient_EditRelease_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EditRelease'
+type MockAPIClient_EditRelease_Call struct {
+	*mock.Call
+}
+
+// EditRelease is a helper method to define mock.On call
+//   - owner string
+//   - repo string
+//   - id int64
+//   - form gitea.EditReleaseOption
+func (_e *MockAPIClient_Expecter) EditRelease(owner interface{}, repo interface{}, id interface{}, form interface{}) *MockAPIClient_EditRelease_Call {
+	return &MockAPIClient_EditRelease_Call{Call: _e.mock.On("EditRelease", owner, repo, id, form)}
+}
+
+func (_c *MockAPIClient_EditRelease_Call) Run(run func(owner string, repo string, id int64, form gitea.EditReleaseOption)) *MockAPIClient_EditRelease_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(int64), args[3].(gitea.EditReleaseOption))
+	})
+	return _c
+}
+
+func (_c *MockAPIClient_EditRelease_Call) Return(_a0 *gitea.Release, _a1 *gitea.Response, _a2 error) *MockAPIClient_EditRelease_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *MockAPIClient_EditRelease_Call) RunAndReturn(run func(string, string, int64, gitea.EditReleaseOption) (*gitea.Release, *gitea.Response, error)) *MockAPIClient_EditRelease_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetReleaseByTag provides a mock function with given fields: owner, repo, tag
+func (_m *MockAPIClient) GetReleaseByTag(owner string, repo string, tag string) (*gitea.Release, *gitea.Response, error) {
+	ret := _m.Called(owner, repo, tag)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetReleaseByTag")
+	}
+
+	var r0 *gitea.Release
+	var r1 *gitea.Response
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string, string, string) (*gitea.Release, *gitea.Response, error)); ok {
+		return rf(owner, repo, tag)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, string) *gitea.Release); ok {
+		r0 = rf(owner, repo, tag)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*gitea.Release)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, string) *gitea.Response); ok {
+		r1 = rf(owner, repo, tag)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*gitea.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(string, string, string) error); ok {
+		r2 = rf(owner, repo, tag)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MockAPIClient_GetReleaseByTag_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetReleaseByTag'
+type MockAPIClient_GetReleaseByTag_Call struct {
+	*mock.Call
+}
+
+// GetReleaseByTag is a helper method to define mock.On call
+//   - owner string
+//   - repo string
+//   - tag string
+func (_e *MockAPIClient_Expecter) GetReleaseByTag(owner interface{}, repo interface{}, tag interface{}) *MockAPIClient_GetReleaseByTag_Call {
+	return &MockAPIClient_GetReleaseByTag_Call{Call: _e.mock.On("GetReleaseByTag", owner, repo, tag)}
+}
+
+func (_c *MockAPIClient_GetReleaseByTag_Call) Run(run func(owner string, repo string, tag string)) *MockAPIClient_GetReleaseByTag_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockAPIClient_GetReleaseByTag_Call) Return(_a0 *gitea.Release, _a1 *gitea.Response, _a2 error) *MockAPIClient_GetReleaseByTag_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *MockAPIClient_GetReleaseByTag_Call) RunAndReturn(run func(string, string, string) (*gitea.Release, *gitea.Response, error)) *MockAPIClient_GetReleaseByTag_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // ListReleaseAttachments provides a mock function with given fields: user, repo, release, opt
 func (_m *MockAPIClient) ListReleaseAttachments(user string, repo string, release int64, opt gitea.ListReleaseAttachmentsOptions) ([]*gitea.Attachment, *gitea.Response, error) {
 	ret := _m.Called(user, repo, release, opt)
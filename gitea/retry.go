@@ -0,0 +1,332 @@
+package gitea
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultRetryMaxAttempts = 3
+	DefaultRetryBackoff     = time.Second
+	DefaultRetryMaxBackoff  = 30 * time.Second
+)
+
+// RetryOptions configures the retry behavior of the RetryClient.
+type RetryOptions struct {
+	// MaxAttempts is the maximum number of attempts per request, including the first one.
+	MaxAttempts int
+	// Backoff is the initial wait time, which is doubled with every retry.
+	Backoff time.Duration
+	// MaxBackoff is the maximum wait time between two attempts. Requests are not retried
+	// if the server asks to wait longer than this.
+	MaxBackoff time.Duration
+}
+
+// RetryClient wraps an APIClient and retries requests that failed with a transient error,
+// e.g. a 5xx status code, a rate limit, a timeout or a connection reset. It uses exponential
+// backoff with full jitter and respects the Retry-After and rate limit headers.
+type RetryClient struct {
+	client APIClient
+	Opt    RetryOptions
+	sleep  func(time.Duration)
+}
+
+var _ APIClient = (*RetryClient)(nil)
+
+// NewRetryClient creates a new RetryClient that wraps the given APIClient.
+func NewRetryClient(client APIClient, opt RetryOptions) *RetryClient {
+	if opt.Backoff <= 0 {
+		opt.Backoff = DefaultRetryBackoff
+	}
+
+	if opt.MaxBackoff <= 0 {
+		opt.MaxBackoff = DefaultRetryMaxBackoff
+	}
+
+	return &RetryClient{
+		client: client,
+		Opt:    opt,
+		sleep:  time.Sleep,
+	}
+}
+
+//nolint:lll
+func (c *RetryClient) ListReleases(owner, repo string, opt gitea.ListReleasesOptions) ([]*gitea.Release, *gitea.Response, error) {
+	var releases []*gitea.Release
+
+	resp, err := c.retry("list releases", func() (*gitea.Response, error) {
+		var (
+			resp *gitea.Response
+			err  error
+		)
+
+		releases, resp, err = c.client.ListReleases(owner, repo, opt)
+
+		return resp, err
+	})
+
+	return releases, resp, err
+}
+
+func (c *RetryClient) GetReleaseByTag(owner, repo, tag string) (*gitea.Release, *gitea.Response, error) {
+	var release *gitea.Release
+
+	resp, err := c.retry("get release", func() (*gitea.Response, error) {
+		var (
+			resp *gitea.Response
+			err  error
+		)
+
+		release, resp, err = c.client.GetReleaseByTag(owner, repo, tag)
+
+		return resp, err
+	})
+
+	return release, resp, err
+}
+
+// CreateRelease creates a release. Before a failed request is retried, it checks
+// whether the release was created by the previous attempt.
+//
+//nolint:lll
+func (c *RetryClient) CreateRelease(owner, repo string, opt gitea.CreateReleaseOption) (*gitea.Release, *gitea.Response, error) {
+	var release *gitea.Release
+
+	attempt := 0
+
+	resp, err := c.retry("create release", func() (*gitea.Response, error) {
+		var (
+			resp *gitea.Response
+			err  error
+		)
+
+		if attempt++; attempt > 1 {
+			if existing, _, err := c.client.GetReleaseByTag(owner, repo, opt.TagName); err == nil && existing != nil {
+				log.Info().Msgf("release was created by previous attempt: %s", opt.TagName)
+
+				release = existing
+
+				return nil, nil
+			}
+		}
+
+		release, resp, err = c.client.CreateRelease(owner, repo, opt)
+
+		return resp, err
+	})
+
+	return release, resp, err
+}
+
+//nolint:lll
+func (c *RetryClient) EditRelease(owner, repo string, id int64, form gitea.EditReleaseOption) (*gitea.Release, *gitea.Response, error) {
+	var release *gitea.Release
+
+	resp, err := c.retry("edit release", func() (*gitea.Response, error) {
+		var (
+			resp *gitea.Response
+			err  error
+		)
+
+		release, resp, err = c.client.EditRelease(owner, repo, id, form)
+
+		return resp, err
+	})
+
+	return release, resp, err
+}
+
+func (c *RetryClient) DeleteRelease(user, repo string, id int64) (*gitea.Response, error) {
+	return c.retry("delete release", func() (*gitea.Response, error) {
+		return c.client.DeleteRelease(user, repo, id)
+	})
+}
+
+//nolint:lll
+func (c *RetryClient) ListReleaseAttachments(user, repo string, release int64, opt gitea.ListReleaseAttachmentsOptions) ([]*gitea.Attachment, *gitea.Response, error) {
+	var attachments []*gitea.Attachment
+
+	resp, err := c.retry("list attachments", func() (*gitea.Response, error) {
+		var (
+			resp *gitea.Response
+			err  error
+		)
+
+		attachments, resp, err = c.client.ListReleaseAttachments(user, repo, release, opt)
+
+		return resp, err
+	})
+
+	return attachments, resp, err
+}
+
+// CreateReleaseAttachment uploads an attachment. Uploads are only retried if the file
+// can be rewound. Before a failed upload is retried, it checks whether the attachment
+// was created by the previous attempt.
+//
+//nolint:lll
+func (c *RetryClient) CreateReleaseAttachment(user, repo string, release int64, file io.Reader, filename string) (*gitea.Attachment, *gitea.Response, error) {
+	var attachment *gitea.Attachment
+
+	seeker, canRewind := file.(io.Seeker)
+	attempt := 0
+
+	resp, err := c.retry("upload "+filename, func() (*gitea.Response, error) {
+		var (
+			resp *gitea.Response
+			err  error
+		)
+
+		if attempt++; attempt > 1 {
+			if !canRewind {
+				return nil, errNoRetry
+			}
+
+			if existing := c.findAttachment(user, repo, release, filename); existing != nil {
+				log.Info().Msgf("artifact was uploaded by previous attempt: %s", filename)
+
+				attachment = existing
+
+				return nil, nil
+			}
+
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, errors.Join(errNoRetry, err)
+			}
+		}
+
+		attachment, resp, err = c.client.CreateReleaseAttachment(user, repo, release, file, filename)
+
+		return resp, err
+	})
+
+	return attachment, resp, err
+}
+
+func (c *RetryClient) DeleteReleaseAttachment(user, repo string, release, id int64) (*gitea.Response, error) {
+	return c.retry("delete attachment", func() (*gitea.Response, error) {
+		return c.client.DeleteReleaseAttachment(user, repo, release, id)
+	})
+}
+
+// errNoRetry is returned by a retry function to stop retrying and return the previous error.
+var errNoRetry = errors.New("request can not be retried")
+
+// retry calls fn until it succeeds, fails with a permanent error or the maximum
+// number of attempts is reached. It returns the response and error of the last attempt.
+func (c *RetryClient) retry(name string, fn func() (*gitea.Response, error)) (*gitea.Response, error) {
+	var (
+		resp *gitea.Response
+		err  error
+	)
+
+	for attempt := 1; ; attempt++ {
+		nextResp, nextErr := fn()
+		if errors.Is(nextErr, errNoRetry) {
+			return resp, err
+		}
+
+		resp, err = nextResp, nextErr
+
+		if err == nil || attempt >= c.Opt.MaxAttempts || !isTransient(resp, err) {
+			return resp, err
+		}
+
+		wait, ok := c.backoff(attempt, resp)
+		if !ok {
+			return resp, err
+		}
+
+		log.Warn().Err(err).Msgf("%s failed, retry %d of %d in %s", name, attempt, c.Opt.MaxAttempts-1, wait)
+
+		c.sleep(wait)
+	}
+}
+
+// backoff returns the wait time before the next attempt. The Retry-After and rate limit
+// headers take precedence over the exponential backoff. It returns false if the server
+// asks to wait longer than MaxBackoff.
+func (c *RetryClient) backoff(attempt int, resp *gitea.Response) (time.Duration, bool) {
+	if wait, ok := retryAfter(resp, time.Now()); ok {
+		return wait, wait <= c.Opt.MaxBackoff
+	}
+
+	backoff := c.Opt.Backoff << (attempt - 1)
+	if backoff <= 0 || backoff > c.Opt.MaxBackoff {
+		backoff = c.Opt.MaxBackoff
+	}
+
+	//nolint:gosec
+	return time.Duration(rand.Int64N(int64(backoff)) + 1), true
+}
+
+func (c *RetryClient) findAttachment(user, repo string, release int64, filename string) *gitea.Attachment {
+	attachments, _, err := c.client.ListReleaseAttachments(user, repo, release, gitea.ListReleaseAttachmentsOptions{})
+	if err != nil {
+		return nil
+	}
+
+	for _, attachment := range attachments {
+		if attachment.Name == filename {
+			return attachment
+		}
+	}
+
+	return nil
+}
+
+// isTransient reports whether a request failed with an error that is worth retrying.
+func isTransient(resp *gitea.Response, err error) bool {
+	if resp != nil && resp.Response != nil {
+		rateLimited := resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0"
+
+		return rateLimited ||
+			resp.StatusCode == http.StatusTooManyRequests ||
+			resp.StatusCode >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// retryAfter returns the wait time requested by the server through the Retry-After
+// header or the X-RateLimit-Remaining and X-RateLimit-Reset headers.
+func retryAfter(resp *gitea.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil || resp.Response == nil {
+		return 0, false
+	}
+
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return max(time.Duration(seconds)*time.Second, 0), true
+		}
+
+		if date, err := http.ParseTime(value); err == nil {
+			return max(date.Sub(now), 0), true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,284 @@
+package gitea
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thegeeklab/wp-gitea-release/gitea/mocks"
+)
+
+func newTestRetryClient(t *testing.T, attempts int) (*RetryClient, *mocks.MockAPIClient, *[]time.Duration) {
+	t.Helper()
+
+	mockClient := mocks.NewMockAPIClient(t)
+	sleeps := make([]time.Duration, 0)
+
+	c := NewRetryClient(mockClient, RetryOptions{
+		MaxAttempts: attempts,
+		Backoff:     time.Second,
+		MaxBackoff:  10 * time.Second,
+	})
+	c.sleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+	}
+
+	return c, mockClient, &sleeps
+}
+
+func response(status int, header map[string]string) *gitea.Response {
+	resp := &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+	}
+
+	for k, v := range header {
+		resp.Header.Set(k, v)
+	}
+
+	return &gitea.Response{Response: resp}
+}
+
+func TestRetryClientRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempts   int
+		responses  []*gitea.Response
+		errs       []error
+		wantCalls  int
+		wantSleeps []time.Duration
+		wantErr    bool
+	}{
+		{
+			name:      "success without retry",
+			attempts:  3,
+			responses: []*gitea.Response{response(http.StatusOK, nil)},
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "retry server error",
+			attempts:  3,
+			responses: []*gitea.Response{response(http.StatusBadGateway, nil), response(http.StatusOK, nil)},
+			errs:      []error{ErrUploadFailed, nil},
+			wantCalls: 2,
+		},
+		{
+			name:      "retry connection reset",
+			attempts:  3,
+			responses: []*gitea.Response{nil, response(http.StatusOK, nil)},
+			errs:      []error{syscall.ECONNRESET, nil},
+			wantCalls: 2,
+		},
+		{
+			name:      "no retry on client error",
+			attempts:  3,
+			responses: []*gitea.Response{response(http.StatusNotFound, nil)},
+			errs:      []error{ErrReleaseNotFound},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:     "give up after max attempts",
+			attempts: 2,
+			responses: []*gitea.Response{
+				response(http.StatusServiceUnavailable, nil),
+				response(http.StatusServiceUnavailable, nil),
+			},
+			errs:      []error{ErrUploadFailed, ErrUploadFailed},
+			wantCalls: 2,
+			wantErr:   true,
+		},
+		{
+			name:     "respect retry after header",
+			attempts: 3,
+			responses: []*gitea.Response{
+				response(http.StatusTooManyRequests, map[string]string{"Retry-After": "5"}),
+				response(http.StatusOK, nil),
+			},
+			errs:       []error{ErrUploadFailed, nil},
+			wantCalls:  2,
+			wantSleeps: []time.Duration{5 * time.Second},
+		},
+		{
+			name:     "no retry if retry after exceeds max backoff",
+			attempts: 3,
+			responses: []*gitea.Response{
+				response(http.StatusTooManyRequests, map[string]string{"Retry-After": "3600"}),
+			},
+			errs:      []error{ErrUploadFailed},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, sleeps := newTestRetryClient(t, tt.attempts)
+			calls := 0
+
+			_, err := c.retry("test", func() (*gitea.Response, error) {
+				calls++
+
+				return tt.responses[calls-1], tt.errs[calls-1]
+			})
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantCalls, calls)
+			assert.Len(t, *sleeps, tt.wantCalls-1)
+
+			if tt.wantSleeps != nil {
+				assert.Equal(t, tt.wantSleeps, *sleeps)
+			}
+
+			for _, d := range *sleeps {
+				assert.Positive(t, d)
+				assert.LessOrEqual(t, d, c.Opt.MaxBackoff)
+			}
+		})
+	}
+}
+
+func TestRetryClientCreateReleaseAttachment(t *testing.T) {
+	t.Run("retry upload from start", func(t *testing.T) {
+		c, mockClient, _ := newTestRetryClient(t, 3)
+		file := bytes.NewReader([]byte("hello"))
+		uploaded := make([]string, 0)
+
+		mockClient.
+			On("CreateReleaseAttachment", "owner", "repo", int64(1), mock.Anything, "file.txt").
+			Return(func(_, _ string, _ int64, r io.Reader, _ string) (*gitea.Attachment, *gitea.Response, error) {
+				b, _ := io.ReadAll(r)
+				uploaded = append(uploaded, string(b))
+
+				if len(uploaded) == 1 {
+					return nil, response(http.StatusBadGateway, nil), ErrUploadFailed
+				}
+
+				return &gitea.Attachment{ID: 2, Name: "file.txt"}, response(http.StatusCreated, nil), nil
+			})
+		mockClient.
+			On("ListReleaseAttachments", "owner", "repo", int64(1), mock.Anything).
+			Return([]*gitea.Attachment{}, nil, nil).
+			Once()
+
+		attachment, _, err := c.CreateReleaseAttachment("owner", "repo", 1, file, "file.txt")
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), attachment.ID)
+		assert.Equal(t, []string{"hello", "hello"}, uploaded)
+	})
+
+	t.Run("skip retry if previous attempt created the asset", func(t *testing.T) {
+		c, mockClient, _ := newTestRetryClient(t, 3)
+
+		mockClient.
+			On("CreateReleaseAttachment", "owner", "repo", int64(1), mock.Anything, "file.txt").
+			Return(nil, nil, syscall.ECONNRESET).
+			Once()
+		mockClient.
+			On("ListReleaseAttachments", "owner", "repo", int64(1), mock.Anything).
+			Return([]*gitea.Attachment{{ID: 3, Name: "file.txt"}}, nil, nil).
+			Once()
+
+		attachment, _, err := c.CreateReleaseAttachment("owner", "repo", 1, bytes.NewReader([]byte("hello")), "file.txt")
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), attachment.ID)
+	})
+
+	t.Run("no retry for readers that can not be rewound", func(t *testing.T) {
+		c, mockClient, _ := newTestRetryClient(t, 3)
+
+		mockClient.
+			On("CreateReleaseAttachment", "owner", "repo", int64(1), mock.Anything, "file.txt").
+			Return(nil, response(http.StatusBadGateway, nil), ErrUploadFailed).
+			Once()
+
+		_, _, err := c.CreateReleaseAttachment("owner", "repo", 1, io.LimitReader(strings.NewReader("hello"), 5), "file.txt")
+
+		assert.ErrorIs(t, err, ErrUploadFailed)
+	})
+}
+
+func TestRetryClientCreateRelease(t *testing.T) {
+	c, mockClient, _ := newTestRetryClient(t, 3)
+	opt := gitea.CreateReleaseOption{TagName: "v1.0.0"}
+
+	mockClient.
+		On("CreateRelease", "owner", "repo", opt).
+		Return(nil, response(http.StatusGatewayTimeout, nil), ErrUploadFailed).
+		Once()
+	mockClient.
+		On("GetReleaseByTag", "owner", "repo", "v1.0.0").
+		Return(&gitea.Release{ID: 1, TagName: "v1.0.0"}, nil, nil).
+		Once()
+
+	release, _, err := c.CreateRelease("owner", "repo", opt)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), release.ID)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		resp   *gitea.Response
+		want   time.Duration
+		wantOk bool
+	}{
+		{
+			name: "no response",
+		},
+		{
+			name: "no header",
+			resp: response(http.StatusServiceUnavailable, nil),
+		},
+		{
+			name:   "retry after seconds",
+			resp:   response(http.StatusTooManyRequests, map[string]string{"Retry-After": "7"}),
+			want:   7 * time.Second,
+			wantOk: true,
+		},
+		{
+			name: "retry after date",
+			resp: response(http.StatusServiceUnavailable, map[string]string{
+				"Retry-After": now.Add(30 * time.Second).Format(http.TimeFormat),
+			}),
+			want:   30 * time.Second,
+			wantOk: true,
+		},
+		{
+			name: "rate limit reset",
+			resp: response(http.StatusForbidden, map[string]string{
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     "1704110420",
+			}),
+			want:   20 * time.Second,
+			wantOk: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := retryAfter(tt.resp, now)
+
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
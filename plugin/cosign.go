@@ -0,0 +1,238 @@
+package plugin
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+var ErrCosignKeyInvalid = errors.New("invalid cosign_key")
+
+const (
+	// SigstoreBundleMediaType is the media type of the written sigstore bundles.
+	SigstoreBundleMediaType = "application/vnd.dev.sigstore.bundle.v0.3+json"
+
+	cosignKeySize   = 32
+	cosignNonceSize = 24
+)
+
+// sigstoreBundle is the JSON encoding of a sigstore bundle with a message signature
+// that is verified with a public key distributed out of band. Byte slices are encoded
+// in standard base64 as required by the protobuf JSON mapping.
+type sigstoreBundle struct {
+	MediaType            string                   `json:"mediaType"`
+	VerificationMaterial sigstoreMaterial         `json:"verificationMaterial"`
+	MessageSignature     sigstoreMessageSignature `json:"messageSignature"`
+}
+
+type sigstoreMaterial struct {
+	PublicKey struct {
+		Hint string `json:"hint"`
+	} `json:"publicKey"`
+}
+
+type sigstoreMessageSignature struct {
+	MessageDigest struct {
+		Algorithm string `json:"algorithm"`
+		Digest    []byte `json:"digest"`
+	} `json:"messageDigest"`
+	Signature []byte `json:"signature"`
+}
+
+type dsseEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     []byte          `json:"payload"`
+	Signatures  []dsseSignature `json:"signatures"`
+}
+
+type dsseSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   []byte `json:"sig"`
+}
+
+// cosignEnvelope is the encrypted private key format written by `cosign generate-key-pair`.
+type cosignEnvelope struct {
+	KDF struct {
+		Name   string `json:"name"`
+		Params struct {
+			N int `json:"N"`
+			R int `json:"r"`
+			P int `json:"p"`
+		} `json:"params"`
+		Salt []byte `json:"salt"`
+	} `json:"kdf"`
+	Cipher struct {
+		Name  string `json:"name"`
+		Nonce []byte `json:"nonce"`
+	} `json:"cipher"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// CosignSigner creates sigstore bundles compatible with `cosign sign-blob --key` without
+// uploading to a transparency log. The bundles can be verified offline with
+// `cosign verify-blob --key cosign.pub --bundle <file> --insecure-ignore-tlog`.
+type CosignSigner struct {
+	key  crypto.Signer
+	hint string
+}
+
+var _ Signer = (*CosignSigner)(nil)
+
+// NewCosignSigner creates a CosignSigner from an encrypted cosign private key or an
+// unencrypted PEM encoded ECDSA or RSA private key.
+func NewCosignSigner(key []byte, passphrase string) (*CosignSigner, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrCosignKeyInvalid)
+	}
+
+	der := block.Bytes
+
+	switch block.Type {
+	case "ENCRYPTED SIGSTORE PRIVATE KEY", "ENCRYPTED COSIGN PRIVATE KEY":
+		var err error
+
+		if der, err = decryptCosignKey(block.Bytes, passphrase); err != nil {
+			return nil, err
+		}
+	case "EC PRIVATE KEY":
+		privateKey, err := x509.ParseECPrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCosignKeyInvalid, err)
+		}
+
+		return newCosignSigner(privateKey)
+	case "RSA PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS1PrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCosignKeyInvalid, err)
+		}
+
+		return newCosignSigner(privateKey)
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCosignKeyInvalid, err)
+	}
+
+	return newCosignSigner(privateKey)
+}
+
+func newCosignSigner(privateKey any) (*CosignSigner, error) {
+	var key crypto.Signer
+
+	switch k := privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		key = k
+	case *rsa.PrivateKey:
+		key = k
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrCosignKeyInvalid, privateKey)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCosignKeyInvalid, err)
+	}
+
+	// The key hint is the SHA256 of the public key as used by cosign.
+	hint := sha256.Sum256(publicKey)
+
+	return &CosignSigner{key: key, hint: base64.StdEncoding.EncodeToString(hint[:])}, nil
+}
+
+// Sign writes a sigstore bundle with the signature of the SHA256 digest of the content.
+func (s *CosignSigner) Sign(w io.Writer, r io.Reader) error {
+	h := sha256.New()
+
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+
+	digest := h.Sum(nil)
+
+	signature, err := s.key.Sign(rand.Reader, digest, crypto.SHA256)
+	if err != nil {
+		return err
+	}
+
+	bundle := sigstoreBundle{MediaType: SigstoreBundleMediaType}
+	bundle.VerificationMaterial.PublicKey.Hint = s.hint
+	bundle.MessageSignature.MessageDigest.Algorithm = "SHA2_256"
+	bundle.MessageSignature.MessageDigest.Digest = digest
+	bundle.MessageSignature.Signature = signature
+
+	return json.NewEncoder(w).Encode(bundle)
+}
+
+func (s *CosignSigner) Ext() string {
+	return ".sigstore.json"
+}
+
+// signDSSE signs the payload with the DSSE pre-authentication encoding and returns the envelope.
+func (s *CosignSigner) signDSSE(payloadType string, payload []byte) (dsseEnvelope, error) {
+	pae := fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload)
+	digest := sha256.Sum256([]byte(pae))
+
+	signature, err := s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return dsseEnvelope{}, err
+	}
+
+	return dsseEnvelope{
+		PayloadType: payloadType,
+		Payload:     payload,
+		Signatures:  []dsseSignature{{KeyID: s.hint, Sig: signature}},
+	}, nil
+}
+
+func decryptCosignKey(data []byte, passphrase string) ([]byte, error) {
+	var envelope cosignEnvelope
+
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCosignKeyInvalid, err)
+	}
+
+	if envelope.KDF.Name != "scrypt" || envelope.Cipher.Name != "nacl/secretbox" {
+		return nil, fmt.Errorf("%w: unsupported encryption %s/%s", ErrCosignKeyInvalid, envelope.KDF.Name, envelope.Cipher.Name)
+	}
+
+	if len(envelope.Cipher.Nonce) != cosignNonceSize {
+		return nil, fmt.Errorf("%w: invalid nonce", ErrCosignKeyInvalid)
+	}
+
+	params := envelope.KDF.Params
+
+	derived, err := scrypt.Key([]byte(passphrase), envelope.KDF.Salt, params.N, params.R, params.P, cosignKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCosignKeyInvalid, err)
+	}
+
+	var (
+		key   [cosignKeySize]byte
+		nonce [cosignNonceSize]byte
+	)
+
+	copy(key[:], derived)
+	copy(nonce[:], envelope.Cipher.Nonce)
+
+	der, ok := secretbox.Open(nil, envelope.Ciphertext, &nonce, &key)
+	if !ok {
+		return nil, fmt.Errorf("%w: decryption failed", ErrCosignKeyInvalid)
+	}
+
+	return der, nil
+}
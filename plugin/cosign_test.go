@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+func TestCosignSigner(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		key        []byte
+		passphrase string
+		publicKey  crypto.PublicKey
+		wantErr    error
+	}{
+		{
+			name:       "encrypted cosign key",
+			key:        cosignTestKey(t, ecdsaKey, testPassphrase),
+			passphrase: testPassphrase,
+			publicKey:  ecdsaKey.Public(),
+		},
+		{
+			name:      "rsa key",
+			key:       pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+			publicKey: rsaKey.Public(),
+		},
+		{
+			name:       "wrong passphrase",
+			key:        cosignTestKey(t, ecdsaKey, testPassphrase),
+			passphrase: "wrong",
+			wantErr:    ErrCosignKeyInvalid,
+		},
+		{
+			name:    "no pem",
+			key:     []byte("cosign.key"),
+			wantErr: ErrCosignKeyInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewCosignSigner(tt.key, tt.passphrase)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			dir := t.TempDir()
+			file := filepath.Join(dir, "app")
+			content := []byte("binary")
+
+			require.NoError(t, os.WriteFile(file, content, 0o600))
+
+			bundles, err := SignFiles(signer, []gitea.Asset{{Path: file, Name: "app-linux-amd64"}})
+			require.NoError(t, err)
+			require.Len(t, bundles, 1)
+			assert.Equal(t, "app-linux-amd64.sigstore.json", bundles[0].Name)
+
+			data, err := os.ReadFile(bundles[0].Path)
+			require.NoError(t, err)
+
+			assert.True(t, verifyBundle(t, tt.publicKey, content, data))
+			assert.False(t, verifyBundle(t, tt.publicKey, []byte("tampered"), data))
+		})
+	}
+}
+
+// verifyBundle verifies a sigstore bundle offline like `cosign verify-blob --insecure-ignore-tlog`.
+func verifyBundle(t *testing.T, publicKey crypto.PublicKey, content, data []byte) bool {
+	t.Helper()
+
+	var bundle sigstoreBundle
+
+	require.NoError(t, json.Unmarshal(data, &bundle))
+	assert.Equal(t, SigstoreBundleMediaType, bundle.MediaType)
+	assert.Equal(t, "SHA2_256", bundle.MessageSignature.MessageDigest.Algorithm)
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	hint := sha256.Sum256(der)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(hint[:]), bundle.VerificationMaterial.PublicKey.Hint)
+
+	digest := sha256.Sum256(content)
+	signature := bundle.MessageSignature.Signature
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	return false
+}
+
+// cosignTestKey encrypts the private key in the format of `cosign generate-key-pair`
+// with low scrypt cost parameters to keep the test fast.
+func cosignTestKey(t *testing.T, privateKey crypto.PrivateKey, passphrase string) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	var envelope cosignEnvelope
+
+	envelope.KDF.Name = "scrypt"
+	envelope.KDF.Params.N = 1024
+	envelope.KDF.Params.R = 8
+	envelope.KDF.Params.P = 1
+	envelope.KDF.Salt = make([]byte, 32)
+	envelope.Cipher.Name = "nacl/secretbox"
+	envelope.Cipher.Nonce = make([]byte, cosignNonceSize)
+
+	_, err = rand.Read(envelope.KDF.Salt)
+	require.NoError(t, err)
+	_, err = rand.Read(envelope.Cipher.Nonce)
+	require.NoError(t, err)
+
+	derived, err := scrypt.Key([]byte(passphrase), envelope.KDF.Salt, 1024, 8, 1, cosignKeySize)
+	require.NoError(t, err)
+
+	var (
+		key   [cosignKeySize]byte
+		nonce [cosignNonceSize]byte
+	)
+
+	copy(key[:], derived)
+	copy(nonce[:], envelope.Cipher.Nonce)
+
+	envelope.Ciphertext = secretbox.Seal(nil, der, &nonce, &key)
+
+	data, err := json.Marshal(envelope)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED SIGSTORE PRIVATE KEY", Bytes: data})
+}
@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"crypto/md5"  //nolint:gosec
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"maps"
+	"slices"
+
+	"github.com/cespare/xxhash/v2"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
+	"lukechampine.com/blake3"
+)
+
+const blake3Size = 32
+
+// HashMethod describes a hash method that can be used to calculate checksums.
+type HashMethod struct {
+	// New returns a new hash instance.
+	New func() hash.Hash
+	// Tag is the algorithm name used in BSD-style tagged checksum lines.
+	Tag string
+	// SumFile is the default name of the combined checksum file. If empty,
+	// DefaultChecksumFileName is used.
+	SumFile string
+}
+
+// hashMethods is the registry of supported hash methods. The tags and sum file names
+// follow the output of the respective coreutils tools, e.g. `b2sum --tag`.
+//
+//nolint:gochecknoglobals
+var hashMethods = map[string]HashMethod{
+	"md5": {
+		New:     md5.New,
+		Tag:     "MD5",
+		SumFile: "md5sum.txt",
+	},
+	"sha1": {
+		New:     sha1.New,
+		Tag:     "SHA1",
+		SumFile: "sha1sum.txt",
+	},
+	"sha224": {
+		New:     sha256.New224,
+		Tag:     "SHA224",
+		SumFile: "sha224sum.txt",
+	},
+	"sha256": {
+		New:     sha256.New,
+		Tag:     "SHA256",
+		SumFile: "sha256sum.txt",
+	},
+	"sha384": {
+		New:     sha512.New384,
+		Tag:     "SHA384",
+		SumFile: "sha384sum.txt",
+	},
+	"sha512": {
+		New:     sha512.New,
+		Tag:     "SHA512",
+		SumFile: "sha512sum.txt",
+	},
+	"sha3-256": {
+		New:     func() hash.Hash { return sha3.New256() },
+		Tag:     "SHA3-256",
+		SumFile: "sha3-256sum.txt",
+	},
+	"sha3-512": {
+		New:     func() hash.Hash { return sha3.New512() },
+		Tag:     "SHA3-512",
+		SumFile: "sha3-512sum.txt",
+	},
+	"adler32": {
+		New: func() hash.Hash { return adler32.New() },
+		Tag: "ADLER32",
+	},
+	"crc32": {
+		New: func() hash.Hash { return crc32.NewIEEE() },
+		Tag: "CRC32",
+	},
+	// blake2b is truncated to 256 bit for compatibility, use blake2b-512 to match b2sum.
+	"blake2b": {
+		New:     func() hash.Hash { return must(blake2b.New256(nil)) },
+		Tag:     "BLAKE2b-256",
+		SumFile: "blake2bsum.txt",
+	},
+	"blake2b-512": {
+		New:     func() hash.Hash { return must(blake2b.New512(nil)) },
+		Tag:     "BLAKE2b",
+		SumFile: "b2sum.txt",
+	},
+	"blake2s": {
+		New:     func() hash.Hash { return must(blake2s.New256(nil)) },
+		Tag:     "BLAKE2s",
+		SumFile: "blake2ssum.txt",
+	},
+	"blake3": {
+		New:     func() hash.Hash { return blake3.New(blake3Size, nil) },
+		Tag:     "BLAKE3",
+		SumFile: "b3sum.txt",
+	},
+	"xxh64": {
+		New:     func() hash.Hash { return xxhash.New() },
+		Tag:     "XXH64",
+		SumFile: "xxh64sum.txt",
+	},
+}
+
+// RegisterHashMethod adds a hash method to the registry or replaces an existing one.
+// It is not safe for concurrent use and must be called before checksums are calculated.
+func RegisterHashMethod(name string, method HashMethod) {
+	hashMethods[name] = method
+}
+
+// HashMethods returns the names of all supported hash methods in alphabetical order.
+func HashMethods() []string {
+	return slices.Sorted(maps.Keys(hashMethods))
+}
+
+func newHash(name string) (hash.Hash, error) {
+	method, ok := hashMethods[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrHashMethodNotSupported, name)
+	}
+
+	return method.New(), nil
+}
+
+// must panics on errors of hash constructors that can only fail for invalid keys.
+func must(h hash.Hash, err error) hash.Hash {
+	if err != nil {
+		panic(err)
+	}
+
+	return h
+}
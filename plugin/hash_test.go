@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"bytes"
+	"hash"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
+)
+
+// TestChecksumGolden compares the checksum files with the output of the reference tools
+// for testdata/checksum/input.txt, e.g. `sha256sum input.txt > sha256.golden`,
+// `b2sum --tag input.txt > blake2b-512.bsd.golden` or `b2sum -l 256 input.txt > blake2b.golden`.
+// The SHA3 golden files were created with `openssl dgst -sha3-256 -r`.
+func TestChecksumGolden(t *testing.T) {
+	goldens, err := filepath.Glob(filepath.Join("testdata", "checksum", "*.golden"))
+	require.NoError(t, err)
+	require.NotEmpty(t, goldens)
+
+	asset := gitea.Asset{Path: filepath.Join("testdata", "checksum", "input.txt"), Name: "input.txt"}
+
+	for _, golden := range goldens {
+		method, style, _ := strings.Cut(strings.TrimSuffix(filepath.Base(golden), ".golden"), ".")
+
+		t.Run(filepath.Base(golden), func(t *testing.T) {
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+
+			dir := t.TempDir()
+
+			_, err = WriteChecksums([]gitea.Asset{asset}, ChecksumOptions{
+				Methods: []string{method},
+				Style:   style,
+				OutDir:  dir,
+			})
+			require.NoError(t, err)
+
+			got, err := os.ReadFile(filepath.Join(dir, hashMethods[method].SumFile))
+			require.NoError(t, err)
+			assert.Equal(t, string(want), string(got))
+		})
+	}
+}
+
+// TestHashMethods checks the hash methods without a reference tool against
+// the test vectors of the BLAKE3 and xxHash specifications.
+func TestHashMethods(t *testing.T) {
+	tests := []struct {
+		method string
+		input  string
+		want   string
+	}{
+		{
+			method: "blake3",
+			input:  "",
+			want:   "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262",
+		},
+		{
+			method: "xxh64",
+			input:  "",
+			want:   "ef46db3751d8e999",
+		},
+		{
+			method: "xxh64",
+			input:  "hello",
+			want:   "26c7827d889f6da3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			got, err := Checksum(strings.NewReader(tt.input), tt.method)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRegisterHashMethod(t *testing.T) {
+	t.Cleanup(func() {
+		delete(hashMethods, "test")
+	})
+
+	RegisterHashMethod("test", HashMethod{
+		New: func() hash.Hash { return must(newHash("sha256")) },
+		Tag: "TEST",
+	})
+
+	assert.Contains(t, HashMethods(), "test")
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app")
+
+	require.NoError(t, os.WriteFile(file, []byte("hello"), 0o600))
+
+	assets, err := WriteChecksums([]gitea.Asset{{Path: file, Name: "app"}}, ChecksumOptions{
+		Methods: []string{"test"},
+		Style:   ChecksumStyleBSD,
+		OutDir:  dir,
+	})
+	require.NoError(t, err)
+	require.Len(t, assets, 2)
+	assert.Equal(t, "testsum.txt", assets[1].Name)
+
+	got, err := os.ReadFile(assets[1].Path)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(got, []byte("TEST (app) = 2cf24dba")))
+}
@@ -4,25 +4,55 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net/url"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gitea-release/changelog"
 	"github.com/thegeeklab/wp-gitea-release/gitea"
 	plugin_file "github.com/thegeeklab/wp-plugin-go/v6/file"
 )
 
+// FileMappingSeparator separates the file pattern from the asset name in file entries.
+const FileMappingSeparator = "=>"
+
 var (
-	ErrPluginEventNotSupported = errors.New("event not supported")
-	ErrFileExistInvalid        = errors.New("invalid file_exist value")
+	ErrPluginEventNotSupported  = errors.New("event not supported")
+	ErrTagRequired              = errors.New("explicit tag required for event")
+	ErrFileExistInvalid         = errors.New("invalid file_exist value")
+	ErrUpdateExistingInvalid    = errors.New("invalid update_existing value")
+	ErrNoteSourceInvalid        = errors.New("invalid note_source value")
+	ErrChangelogMissingInvalid  = errors.New("invalid changelog_missing value")
+	ErrUploadConcurrencyInvalid = errors.New("invalid upload_concurrency value")
+	ErrPruneKeepInvalid         = errors.New("invalid prune_keep pattern")
+	ErrFilesMissingInvalid      = errors.New("invalid files_missing value")
+	ErrFilesMinCountInvalid     = errors.New("invalid files_min_count value")
+	ErrFilesMissing             = errors.New("not enough files found for pattern")
+	ErrFilesExcludeInvalid      = errors.New("invalid files_exclude pattern")
+	ErrAssetNameInvalid         = errors.New("invalid asset name")
+	ErrFileMappingInvalid       = errors.New("invalid file mapping")
 )
 
 func (p *Plugin) run(ctx context.Context) error {
-	if err := p.FlagsFromContext(); err != nil {
+	if err := p.Validate(); err != nil {
+		if errors.Is(err, ErrPluginEventNotSupported) && p.Settings.SkipUnsupported {
+			log.Info().Msgf("skip unsupported event: %s", p.Settings.Event)
+
+			return nil
+		}
+
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
-	if err := p.Validate(); err != nil {
+	if err := p.FlagsFromContext(); err != nil {
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
@@ -38,19 +68,162 @@ func (p *Plugin) Validate() error {
 	var err error
 
 	fileExistsValues := map[string]bool{
-		"overwrite": true,
-		"fail":      true,
-		"skip":      true,
+		"overwrite":          true,
+		"replace-if-changed": true,
+		"fail":               true,
+		"skip":               true,
+	}
+
+	updateExistingValues := map[string]bool{
+		"off":     true,
+		"merge":   true,
+		"replace": true,
+	}
+
+	noteSourceValues := map[string]bool{
+		"note":      true,
+		"changelog": true,
+	}
+
+	changelogMissingValues := map[string]bool{
+		"warn": true,
+		"fail": true,
+	}
+
+	checksumFormatValues := map[string]bool{
+		ChecksumFormatCombined: true,
+		ChecksumFormatPerFile:  true,
+		ChecksumFormatBoth:     true,
 	}
 
-	if p.Settings.Event != "tag" {
-		return fmt.Errorf("%w: %s", ErrPluginEventNotSupported, p.Metadata.Pipeline.Event)
+	checksumStyleValues := map[string]bool{
+		ChecksumStyleGNU: true,
+		ChecksumStyleBSD: true,
+	}
+
+	signMethodValues := map[string]bool{
+		"":                 true,
+		SignMethodGPG:      true,
+		SignMethodMinisign: true,
+		SignMethodSignify:  true,
+	}
+
+	sbomFormatValues := map[string]bool{
+		"":                  true,
+		SBOMFormatCycloneDX: true,
+		SBOMFormatSPDX:      true,
+	}
+
+	filesMissingValues := map[string]bool{
+		"ignore": true,
+		"warn":   true,
+		"fail":   true,
+	}
+
+	if p.Settings.Tag, err = p.render(p.Settings.Tag); err != nil {
+		return fmt.Errorf("error while rendering tag: %w", err)
+	}
+
+	if p.Settings.Tag, err = p.resolveTag(); err != nil {
+		return err
 	}
 
 	if !fileExistsValues[p.Settings.FileExists] {
 		return ErrFileExistInvalid
 	}
 
+	if !updateExistingValues[p.Settings.UpdateExisting] {
+		return ErrUpdateExistingInvalid
+	}
+
+	if !noteSourceValues[p.Settings.NoteSource] {
+		return ErrNoteSourceInvalid
+	}
+
+	if !changelogMissingValues[p.Settings.ChangelogMissing] {
+		return ErrChangelogMissingInvalid
+	}
+
+	if p.Settings.UploadConcurrency < 1 {
+		return fmt.Errorf("%w: %d", ErrUploadConcurrencyInvalid, p.Settings.UploadConcurrency)
+	}
+
+	if !checksumFormatValues[p.Settings.ChecksumFormat] {
+		return ErrChecksumFormatInvalid
+	}
+
+	if !checksumStyleValues[p.Settings.ChecksumStyle] {
+		return ErrChecksumStyleInvalid
+	}
+
+	if err := ValidateChecksumFileName(p.Settings.ChecksumFile, p.Settings.Checksum); err != nil {
+		return err
+	}
+
+	if !filesMissingValues[p.Settings.FilesMissing] {
+		return ErrFilesMissingInvalid
+	}
+
+	if p.Settings.FilesMinCount < 0 {
+		return fmt.Errorf("%w: %d", ErrFilesMinCountInvalid, p.Settings.FilesMinCount)
+	}
+
+	if p.Settings.filesMinCounts, err = parseFilesMinCounts(p.Settings.FilesMinCounts); err != nil {
+		return err
+	}
+
+	for _, pattern := range p.Settings.FilesExclude {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("%w: %q", ErrFilesExcludeInvalid, pattern)
+		}
+	}
+
+	for _, pattern := range p.Settings.PruneKeep {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: %q", ErrPruneKeepInvalid, pattern)
+		}
+	}
+
+	if !sbomFormatValues[p.Settings.SBOMFormat] {
+		return ErrSBOMFormatInvalid
+	}
+
+	if !signMethodValues[p.Settings.SignMethod] {
+		return ErrSignMethodInvalid
+	}
+
+	if p.Settings.SignMethod != "" {
+		key, _, err := plugin_file.ReadStringOrFile(p.Settings.SignKey)
+		if err != nil {
+			return fmt.Errorf("error while reading sign key: %w", err)
+		}
+
+		if p.Settings.signer, err = NewSigner(p.Settings.SignMethod, []byte(key), p.Settings.SignPassphrase); err != nil {
+			return err
+		}
+	}
+
+	if p.Settings.CosignKey != "" {
+		key, _, err := plugin_file.ReadStringOrFile(p.Settings.CosignKey)
+		if err != nil {
+			return fmt.Errorf("error while reading cosign key: %w", err)
+		}
+
+		if p.Settings.cosignSigner, err = NewCosignSigner([]byte(key), p.Settings.CosignPassphrase); err != nil {
+			return err
+		}
+	}
+
+	if p.Settings.NoteSource == "changelog" {
+		if p.Settings.changelogGroups, err = changelog.ParseGroups(p.Settings.ChangelogGroups); err != nil {
+			return err
+		}
+
+		if p.Settings.changelogExclude, err = changelog.ParseExclude(p.Settings.ChangelogExclude); err != nil {
+			return err
+		}
+	}
+
 	if p.Settings.Note != "" {
 		if p.Settings.Note, _, err = plugin_file.ReadStringOrFile(p.Settings.Note); err != nil {
 			return fmt.Errorf("error while reading %s: %w", p.Settings.Note, err)
@@ -66,22 +239,151 @@ func (p *Plugin) Validate() error {
 	return nil
 }
 
+// resolveTag determines the release tag for the current pipeline event.
+// An explicit tag always takes precedence and is used for any event. Otherwise, the event
+// has to be in the list of events. Tag events use the commit ref and deployment events
+// use the deploy target. All other events require an explicit tag.
+func (p *Plugin) resolveTag() (string, error) {
+	if p.Settings.Tag != "" {
+		return p.Settings.Tag, nil
+	}
+
+	if !slices.Contains(p.Settings.Events, p.Settings.Event) {
+		return "", fmt.Errorf("%w: %s", ErrPluginEventNotSupported, p.Settings.Event)
+	}
+
+	switch p.Settings.Event {
+	case "tag":
+		return strings.TrimPrefix(p.Settings.CommitRef, "refs/tags/"), nil
+	case "deployment":
+		if p.Settings.DeployTarget != "" {
+			return p.Settings.DeployTarget, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrTagRequired, p.Settings.Event)
+}
+
+// buildNote renders the configured note if templates are enabled and appends the release
+// section of the changelog file and the changelog generated from the git history if enabled.
+func (p *Plugin) buildNote() (string, error) {
+	note, err := p.renderText(p.Settings.Note)
+	if err != nil {
+		return "", fmt.Errorf("error while rendering note: %w", err)
+	}
+
+	parts := []string{note}
+
+	if p.Settings.NoteFromChangelog != "" {
+		section, err := p.changelogSection()
+		if err != nil {
+			return "", err
+		}
+
+		parts = append(parts, section)
+	}
+
+	if p.Settings.NoteSource == "changelog" {
+		changes, err := changelog.Generate(changelog.Options{
+			Tag:     p.Settings.Tag,
+			Groups:  p.Settings.changelogGroups,
+			Exclude: p.Settings.changelogExclude,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to generate changelog: %w", err)
+		}
+
+		parts = append(parts, changes)
+	}
+
+	// The configured note is kept as introduction above the changelog sections.
+	parts = slices.DeleteFunc(parts, func(part string) bool {
+		return strings.TrimSpace(part) == ""
+	})
+
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// changelogSection extracts the section of the current tag from the changelog file.
+// A missing section is handled according to the ChangelogMissing setting.
+func (p *Plugin) changelogSection() (string, error) {
+	handle, err := os.Open(p.Settings.NoteFromChangelog)
+	if err != nil {
+		return "", fmt.Errorf("failed to read changelog: %w", err)
+	}
+	defer handle.Close()
+
+	section, err := changelog.ExtractSection(handle, p.Settings.Tag, p.Settings.ChangelogHeadings)
+	if errors.Is(err, changelog.ErrSectionNotFound) && p.Settings.ChangelogMissing == "warn" {
+		log.Warn().Msgf("no section found in %s for tag: %s", p.Settings.NoteFromChangelog, p.Settings.Tag)
+
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to extract changelog section: %w", err)
+	}
+
+	return section, nil
+}
+
 // Execute provides the implementation of the plugin.
 func (p *Plugin) Execute() error {
-	client, err := gitea.NewClient(p.Settings.baseURL.String(), p.Settings.APIKey, p.Network.Client)
+	var err error
+
+	if p.Settings.Title, err = p.renderText(p.Settings.Title); err != nil {
+		return fmt.Errorf("error while rendering title: %w", err)
+	}
+
+	// Gitea rejects releases without a title, the tag is used as fallback for all events.
+	if strings.TrimSpace(p.Settings.Title) == "" {
+		p.Settings.Title = p.Settings.Tag
+	}
+
+	if p.Settings.Note, err = p.buildNote(); err != nil {
+		return err
+	}
+
+	// Fail before any change is made to the release if the asset names are not unique.
+	if err := gitea.CheckAssetNames(p.Settings.assets); err != nil {
+		return fmt.Errorf("failed to upload the files: %w", err)
+	}
+
+	client, err := gitea.NewClient(p.Settings.baseURL.String(), p.Settings.APIKey, p.Network.Client, gitea.RetryOptions{
+		MaxAttempts: p.Settings.RetryMaxAttempts,
+		Backoff:     p.Settings.RetryBackoff,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create Gitea client: %w", err)
 	}
 
+	if p.Settings.DryRun {
+		log.Info().Msg("dry run enabled, no changes are applied to Gitea")
+
+		dryRun := client.DryRun()
+
+		defer logPlan(p.Settings.Tag, dryRun)
+	}
+
 	client.Release.Opt = gitea.ReleaseOptions{
-		Owner:      p.Metadata.Repository.Owner,
-		Repo:       p.Metadata.Repository.Name,
-		Tag:        strings.TrimPrefix(p.Settings.CommitRef, "refs/tags/"),
-		Draft:      p.Settings.Draft,
-		Prerelease: p.Settings.PreRelease,
-		FileExists: p.Settings.FileExists,
-		Title:      p.Settings.Title,
-		Note:       p.Settings.Note,
+		Owner:             p.Metadata.Repository.Owner,
+		Repo:              p.Metadata.Repository.Name,
+		Tag:               p.Settings.Tag,
+		Target:            p.Settings.Target,
+		Draft:             p.Settings.Draft,
+		Prerelease:        p.Settings.PreRelease,
+		Atomic:            p.Settings.Atomic,
+		FileExists:        p.Settings.FileExists,
+		UpdateExisting:    p.Settings.UpdateExisting,
+		UploadConcurrency: p.Settings.UploadConcurrency,
+		Prune:             p.Settings.Prune,
+		PruneKeep:         p.Settings.PruneKeep,
+		PruneDryRun:       p.Settings.PruneDryRun,
+		Title:             p.Settings.Title,
+		Note:              p.Settings.Note,
+		Checksum: func(r io.Reader) (string, error) {
+			return Checksum(r, "sha256")
+		},
 	}
 
 	release, err := client.Release.Find()
@@ -89,21 +391,60 @@ func (p *Plugin) Execute() error {
 		return fmt.Errorf("failed to retrieve release: %w", err)
 	}
 
+	created := false
+
 	// If no release was found by that tag, create a new one.
 	if release == nil {
 		release, err = client.Release.Create()
 		if err != nil {
 			return fmt.Errorf("failed to create release: %w", err)
 		}
+
+		created = true
+	} else {
+		release, err = client.Release.Update(release)
+		if err != nil {
+			return fmt.Errorf("failed to update release: %w", err)
+		}
 	}
 
-	if err := client.Release.AddAttachments(release.ID, p.Settings.files); err != nil {
+	if err := client.Release.AddAttachments(release.ID, p.Settings.assets); err != nil {
+		// Remove the incomplete draft release to never leave a broken release behind.
+		if created && p.Settings.Atomic && p.Settings.AtomicCleanup {
+			if derr := client.Release.Delete(release); derr != nil {
+				err = errors.Join(err, derr)
+			}
+		}
+
 		return fmt.Errorf("failed to upload the files: %w", err)
 	}
 
+	// Drafts left by a failed atomic run are published as well.
+	if p.Settings.Atomic {
+		if _, err := client.Release.Publish(release); err != nil {
+			return fmt.Errorf("failed to publish release: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// logPlan logs the actions recorded by the dry run client.
+func logPlan(tag string, dryRun *gitea.DryRunClient) {
+	plan := dryRun.Plan()
+	if len(plan) == 0 {
+		log.Info().Msgf("dry run: no changes planned for release: %s", tag)
+
+		return
+	}
+
+	log.Info().Msgf("dry run: planned changes for release: %s", tag)
+
+	for _, action := range plan {
+		log.Info().Msgf("- %s", action)
+	}
+}
+
 func (p *Plugin) FlagsFromContext() error {
 	var err error
 
@@ -118,30 +459,291 @@ func (p *Plugin) FlagsFromContext() error {
 		return fmt.Errorf("failed to parse base url: %w", err)
 	}
 
-	var files []string
+	mappings, err := parseFiles(p.App.StringSlice("files"))
+	if err != nil {
+		return err
+	}
+
+	files, err := p.globFiles(mappings)
+	if err != nil {
+		return err
+	}
+
+	assets, err := p.newAssets(files)
+	if err != nil {
+		return err
+	}
 
-	rawFiles := p.App.StringSlice("files")
-	for _, glob := range rawFiles {
-		globed, err := filepath.Glob(glob)
+	count := len(assets)
+	sums := AssetSums{}
+
+	// The SBOM and provenance always list the SHA256 checksum. It is calculated together
+	// with the configured checksums, so every file is read only once.
+	if p.Settings.SBOMFormat != "" || p.Settings.Provenance {
+		if err := sums.Add(assets, append([]string{"sha256"}, p.Settings.Checksum...)); err != nil {
+			return fmt.Errorf("failed to calculate checksums: %w", err)
+		}
+	}
+
+	if len(p.Settings.Checksum) > 0 {
+		assets, err = WriteChecksums(assets, ChecksumOptions{
+			Methods:  p.Settings.Checksum,
+			Format:   p.Settings.ChecksumFormat,
+			Style:    p.Settings.ChecksumStyle,
+			OutDir:   p.Settings.ChecksumDir,
+			FileName: p.Settings.ChecksumFile,
+			Sums:     sums,
+		})
 		if err != nil {
-			return fmt.Errorf("failed to glob %s: %w", glob, err)
+			return fmt.Errorf("failed to write checksums: %w", err)
 		}
+	}
 
-		if globed != nil {
-			files = append(files, globed...)
+	if p.Settings.SBOMFormat != "" {
+		sbom, err := WriteSBOM(assets, SBOMOptions{
+			Format:        p.Settings.SBOMFormat,
+			Path:          p.sbomFile(),
+			Name:          path.Join(p.Metadata.Repository.Owner, p.Metadata.Repository.Name),
+			Version:       p.Settings.Tag,
+			RepositoryURL: p.Metadata.Repository.URL,
+			Commit:        p.Metadata.Curr.SHA,
+			Methods:       p.Settings.Checksum,
+			GoModules:     p.Settings.SBOMGoModules,
+			Sums:          sums,
+		})
+		if err != nil {
+			return err
 		}
+
+		assets = append(assets, sbom)
 	}
 
-	if len(p.Settings.Checksum) > 0 {
-		var err error
+	// Sigstore bundles are written for the assets, checksum files and SBOM. The provenance is
+	// signed as DSSE envelope with the cosign key already.
+	released := assets
 
-		files, err = WriteChecksums(files, p.Settings.Checksum, "")
+	if p.Settings.Provenance {
+		provenance, err := WriteProvenance(assets, ProvenanceOptions{
+			Path:     ProvenanceFileName,
+			Metadata: p.Metadata,
+			Signer:   p.Settings.cosignSigner,
+			Sums:     sums,
+		})
 		if err != nil {
-			return fmt.Errorf("failed to write checksums: %w", err)
+			return err
 		}
+
+		assets = append(assets, provenance)
 	}
 
-	p.Settings.files = files
+	if p.Settings.signer != nil {
+		// Only the checksum files, SBOM and provenance are signed by default, they cover the assets already.
+		signed := assets[count:]
+		if p.Settings.SignAssets {
+			signed = assets
+		}
+
+		if len(signed) == 0 {
+			log.Warn().Msg("no files to sign, enable checksums or sign_assets")
+		}
+
+		signatures, err := SignFiles(p.Settings.signer, signed)
+		if err != nil {
+			return fmt.Errorf("failed to sign files: %w", err)
+		}
+
+		assets = append(assets, signatures...)
+	}
+
+	if p.Settings.cosignSigner != nil {
+		bundles, err := SignFiles(p.Settings.cosignSigner, released)
+		if err != nil {
+			return fmt.Errorf("failed to write sigstore bundles: %w", err)
+		}
+
+		assets = append(assets, bundles...)
+	}
+
+	p.Settings.assets = assets
+	p.Settings.files = make([]string, 0, len(assets))
+
+	for _, asset := range assets {
+		p.Settings.files = append(p.Settings.files, asset.Path)
+	}
 
 	return nil
 }
+
+// newAssets names the matched files by the asset name template. The matched files are
+// set as Files before, so they are available in the asset name templates.
+func (p *Plugin) newAssets(files []string) ([]gitea.Asset, error) {
+	p.Settings.files = files
+
+	assets := make([]gitea.Asset, 0, len(files))
+
+	for _, file := range files {
+		name, err := p.assetName(file)
+		if err != nil {
+			return nil, fmt.Errorf("error while rendering asset name: %w", err)
+		}
+
+		assets = append(assets, gitea.Asset{Path: file, Name: name})
+	}
+
+	return assets, nil
+}
+
+// sbomFile returns the path of the SBOM file, which defaults to the conventional
+// file name of the SBOM format in the working directory.
+func (p *Plugin) sbomFile() string {
+	if p.Settings.SBOMFile != "" {
+		return p.Settings.SBOMFile
+	}
+
+	if p.Settings.SBOMFormat == SBOMFormatSPDX {
+		return "sbom.spdx.json"
+	}
+
+	return "sbom.cdx.json"
+}
+
+// fileMapping assigns an asset name template to the files matched by a pattern.
+// The name is empty for file entries without a mapping.
+type fileMapping struct {
+	pattern string
+	name    string
+}
+
+// parseFiles splits file entries in the format "<pattern>[=><name>]" into the pattern
+// and the optional asset name template.
+func parseFiles(entries []string) ([]fileMapping, error) {
+	mappings := make([]fileMapping, 0, len(entries))
+
+	for _, entry := range entries {
+		pattern, name, ok := strings.Cut(entry, FileMappingSeparator)
+		pattern, name = strings.TrimSpace(pattern), strings.TrimSpace(name)
+
+		if ok && (pattern == "" || name == "") {
+			return nil, fmt.Errorf("%w: %q", ErrFileMappingInvalid, entry)
+		}
+
+		mappings = append(mappings, fileMapping{pattern: pattern, name: name})
+	}
+
+	return mappings, nil
+}
+
+// parseFilesMinCounts parses entries in the format "<pattern>:<count>" into a map of the
+// cleaned patterns to the minimum number of files. The count is separated by the last colon.
+func parseFilesMinCounts(entries []string) (map[string]int, error) {
+	counts := make(map[string]int, len(entries))
+
+	for _, entry := range entries {
+		i := strings.LastIndex(entry, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrFilesMinCountInvalid, entry)
+		}
+
+		pattern := strings.TrimSpace(entry[:i])
+
+		count, err := strconv.Atoi(strings.TrimSpace(entry[i+1:]))
+		if pattern == "" || err != nil || count < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrFilesMinCountInvalid, entry)
+		}
+
+		counts[filepath.Clean(pattern)] = count
+	}
+
+	return counts, nil
+}
+
+// globFiles expands the given glob patterns, which support "**" to match any number
+// of directories. Directories and files matching any FilesExclude pattern are omitted.
+// Files are ordered by pattern and then by path, and files matched by more than one
+// pattern are only included once. Patterns that match fewer files than their minimum
+// count from FilesMinCounts, or FilesMinCount otherwise, are handled according to the
+// FilesMissing setting. The asset name template of the first mapping that matched a file is stored for assetName.
+func (p *Plugin) globFiles(mappings []fileMapping) ([]string, error) {
+	var files []string
+
+	seen := make(map[string]bool)
+	missing := make([]string, 0)
+	p.Settings.fileNames = make(map[string]string)
+
+	patterns := make(map[string]bool, len(mappings))
+	for _, m := range mappings {
+		patterns[filepath.Clean(m.pattern)] = true
+	}
+
+	for _, pattern := range slices.Sorted(maps.Keys(p.Settings.filesMinCounts)) {
+		if !patterns[pattern] {
+			return nil, fmt.Errorf("%w: pattern %q is not in files", ErrFilesMinCountInvalid, pattern)
+		}
+	}
+
+	for _, m := range mappings {
+		glob := m.pattern
+
+		minCount, ok := p.Settings.filesMinCounts[filepath.Clean(glob)]
+		if !ok {
+			minCount = p.Settings.FilesMinCount
+		}
+
+		globed, err := doublestar.FilepathGlob(glob, doublestar.WithFilesOnly())
+		if err != nil {
+			return nil, fmt.Errorf("failed to glob %s: %w", glob, err)
+		}
+
+		globed = slices.DeleteFunc(globed, p.excluded)
+		slices.Sort(globed)
+
+		if len(globed) < minCount {
+			missing = append(missing, fmt.Sprintf("%q (%d of %d)", glob, len(globed), minCount))
+		}
+
+		for _, file := range globed {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+
+			if _, ok := p.Settings.fileNames[file]; !ok && m.name != "" {
+				p.Settings.fileNames[file] = m.name
+			}
+		}
+	}
+
+	if len(missing) == 0 {
+		return files, nil
+	}
+
+	switch p.Settings.FilesMissing {
+	case "fail":
+		return nil, fmt.Errorf("%w: %s", ErrFilesMissing, strings.Join(missing, ", "))
+	case "warn":
+		for _, m := range missing {
+			log.Warn().Msgf("not enough files found for pattern: %s", m)
+		}
+	}
+
+	return files, nil
+}
+
+// excluded reports whether the file matches any of the FilesExclude patterns.
+// Patterns without a path separator are matched against the file name only.
+func (p *Plugin) excluded(file string) bool {
+	file = filepath.ToSlash(file)
+
+	for _, pattern := range p.Settings.FilesExclude {
+		name := file
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(file)
+		}
+
+		if ok, _ := doublestar.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,908 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	gitea_sdk "code.gitea.io/sdk/gitea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gitea-release/changelog"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+)
+
+func newTestPlugin(settings *Settings) *Plugin {
+	p := &Plugin{
+		Plugin:   &plugin_base.Plugin{},
+		Settings: settings,
+	}
+
+	p.Network.Client = &http.Client{}
+
+	return p
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings *Settings
+		wantTag  string
+		wantErr  error
+	}{
+		{
+			name: "tag event uses commit ref",
+			settings: &Settings{
+				Event:     "tag",
+				CommitRef: "refs/tags/v1.0.0",
+			},
+			wantTag: "v1.0.0",
+		},
+		{
+			name: "tag event with explicit tag",
+			settings: &Settings{
+				Event:     "tag",
+				CommitRef: "refs/tags/v1.0.0",
+				Tag:       "v1.0.1",
+			},
+			wantTag: "v1.0.1",
+		},
+		{
+			name: "push event with explicit tag",
+			settings: &Settings{
+				Event:     "push",
+				CommitRef: "refs/heads/main",
+				Tag:       "v1.1.0",
+				Target:    "3d4e5f6",
+			},
+			wantTag: "v1.1.0",
+		},
+		{
+			name: "push event with tag template",
+			settings: &Settings{
+				Event:     "push",
+				CommitRef: "refs/heads/main",
+				Tag:       `{{ "1.2.0" | printf "v%s" }}`,
+			},
+			wantTag: "v1.2.0",
+		},
+		{
+			name: "push event without tag",
+			settings: &Settings{
+				Event:     "push",
+				CommitRef: "refs/heads/main",
+			},
+			wantErr: ErrPluginEventNotSupported,
+		},
+		{
+			name: "push event in events requires tag",
+			settings: &Settings{
+				Event:     "push",
+				Events:    []string{"push"},
+				CommitRef: "refs/heads/main",
+			},
+			wantErr: ErrTagRequired,
+		},
+		{
+			name: "manual event requires tag",
+			settings: &Settings{
+				Event:     "manual",
+				Events:    []string{"tag", "manual"},
+				CommitRef: "refs/heads/main",
+			},
+			wantErr: ErrTagRequired,
+		},
+		{
+			name: "deployment event uses deploy target",
+			settings: &Settings{
+				Event:        "deployment",
+				Events:       []string{"deployment"},
+				CommitRef:    "refs/heads/main",
+				DeployTarget: "v2.0.0",
+			},
+			wantTag: "v2.0.0",
+		},
+		{
+			name: "event not in events list",
+			settings: &Settings{
+				Event:     "pull_request",
+				Events:    []string{"tag", "push"},
+				CommitRef: "refs/pull/1/head",
+			},
+			wantErr: ErrPluginEventNotSupported,
+		},
+		{
+			name: "explicit tag bypasses events list",
+			settings: &Settings{
+				Event:     "manual",
+				Events:    []string{"tag"},
+				CommitRef: "refs/heads/main",
+				Tag:       "v1.1.0",
+			},
+			wantTag: "v1.1.0",
+		},
+		{
+			name: "invalid note source",
+			settings: &Settings{
+				Event:      "tag",
+				Events:     []string{"tag"},
+				CommitRef:  "refs/tags/v1.0.0",
+				NoteSource: "commits",
+			},
+			wantErr: ErrNoteSourceInvalid,
+		},
+		{
+			name: "invalid changelog group",
+			settings: &Settings{
+				Event:           "tag",
+				Events:          []string{"tag"},
+				CommitRef:       "refs/tags/v1.0.0",
+				NoteSource:      "changelog",
+				ChangelogGroups: []string{"Features"},
+			},
+			wantErr: changelog.ErrGroupInvalid,
+		},
+		{
+			name: "invalid prune keep pattern",
+			settings: &Settings{
+				Event:     "tag",
+				Events:    []string{"tag"},
+				CommitRef: "refs/tags/v1.0.0",
+				Prune:     true,
+				PruneKeep: []string{"[*.sh"},
+			},
+			wantErr: ErrPruneKeepInvalid,
+		},
+		{
+			name: "invalid files missing",
+			settings: &Settings{
+				Event:        "tag",
+				Events:       []string{"tag"},
+				CommitRef:    "refs/tags/v1.0.0",
+				FilesMissing: "skip",
+			},
+			wantErr: ErrFilesMissingInvalid,
+		},
+		{
+			name: "invalid files min counts",
+			settings: &Settings{
+				Event:          "tag",
+				Events:         []string{"tag"},
+				CommitRef:      "refs/tags/v1.0.0",
+				FilesMinCounts: []string{"dist/*.tar.gz:-1"},
+			},
+			wantErr: ErrFilesMinCountInvalid,
+		},
+		{
+			name: "invalid checksum format",
+			settings: &Settings{
+				Event:          "tag",
+				Events:         []string{"tag"},
+				CommitRef:      "refs/tags/v1.0.0",
+				ChecksumFormat: "sidecar",
+			},
+			wantErr: ErrChecksumFormatInvalid,
+		},
+		{
+			name: "invalid sbom format",
+			settings: &Settings{
+				Event:      "tag",
+				Events:     []string{"tag"},
+				CommitRef:  "refs/tags/v1.0.0",
+				SBOMFormat: "swid",
+			},
+			wantErr: ErrSBOMFormatInvalid,
+		},
+		{
+			name: "invalid sign method",
+			settings: &Settings{
+				Event:      "tag",
+				Events:     []string{"tag"},
+				CommitRef:  "refs/tags/v1.0.0",
+				SignMethod: "pgp",
+			},
+			wantErr: ErrSignMethodInvalid,
+		},
+		{
+			name: "sign method without key",
+			settings: &Settings{
+				Event:      "tag",
+				Events:     []string{"tag"},
+				CommitRef:  "refs/tags/v1.0.0",
+				SignMethod: "minisign",
+			},
+			wantErr: ErrSignKeyRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.settings.FileExists = "overwrite"
+			tt.settings.UpdateExisting = "off"
+			tt.settings.UploadConcurrency = 1
+
+			if tt.settings.Events == nil {
+				tt.settings.Events = []string{"tag"}
+			}
+
+			if tt.settings.NoteSource == "" {
+				tt.settings.NoteSource = "note"
+			}
+
+			if tt.settings.ChangelogMissing == "" {
+				tt.settings.ChangelogMissing = "warn"
+			}
+
+			if tt.settings.FilesMissing == "" {
+				tt.settings.FilesMissing = "fail"
+			}
+
+			if tt.settings.ChecksumFormat == "" {
+				tt.settings.ChecksumFormat = "combined"
+			}
+
+			if tt.settings.ChecksumStyle == "" {
+				tt.settings.ChecksumStyle = "gnu"
+			}
+
+			p := newTestPlugin(tt.settings)
+
+			err := p.Validate()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTag, p.Settings.Tag)
+		})
+	}
+}
+
+func TestRunSkipUnsupported(t *testing.T) {
+	p := newTestPlugin(&Settings{
+		Event:           "pull_request",
+		Events:          []string{"tag"},
+		SkipUnsupported: true,
+		FileExists:      "overwrite",
+		UpdateExisting:  "off",
+	})
+
+	assert.NoError(t, p.run(t.Context()))
+}
+
+func TestBuildNote(t *testing.T) {
+	changelogFile := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	content := "# Changelog\n\n## [1.1.0] - 2024-02-01\n\n- New flag\n\n## [1.0.0] - 2024-01-01\n\n- Initial release\n"
+
+	if err := os.WriteFile(changelogFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		settings *Settings
+		want     string
+		wantErr  error
+	}{
+		{
+			name: "note only",
+			settings: &Settings{
+				Tag:      "v1.1.0",
+				Note:     "Release {{ .Tag }}",
+				Template: true,
+			},
+			want: "Release v1.1.0",
+		},
+		{
+			name: "literal note without template",
+			settings: &Settings{
+				Tag:  "v1.1.0",
+				Note: "Set `image.tag: {{ .Values.image.tag }}` in your values.",
+			},
+			want: "Set `image.tag: {{ .Values.image.tag }}` in your values.",
+		},
+		{
+			name: "note and changelog section",
+			settings: &Settings{
+				Tag:               "v1.1.0",
+				Note:              "Release {{ .Tag }}",
+				Template:          true,
+				NoteFromChangelog: changelogFile,
+				ChangelogMissing:  "warn",
+			},
+			want: "Release v1.1.0\n\n- New flag",
+		},
+		{
+			name: "missing section with warning",
+			settings: &Settings{
+				Tag:               "v2.0.0",
+				Note:              "Release {{ .Tag }}",
+				Template:          true,
+				NoteFromChangelog: changelogFile,
+				ChangelogMissing:  "warn",
+			},
+			want: "Release v2.0.0",
+		},
+		{
+			name: "missing section with error",
+			settings: &Settings{
+				Tag:               "v2.0.0",
+				NoteFromChangelog: changelogFile,
+				ChangelogMissing:  "fail",
+			},
+			wantErr: changelog.ErrSectionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(tt.settings)
+
+			note, err := p.buildNote()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, note)
+		})
+	}
+}
+
+func TestGlobFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{
+		"app-amd64.tar.gz", "app-arm64.tar.gz", "README.md",
+		"linux/app.tar.gz", "linux/app.sig.tmp", "linux/arm/app.tar.gz", "dir.tar.gz/file",
+	} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o700))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("hello"), 0o600))
+	}
+
+	tests := []struct {
+		name      string
+		patterns  []string
+		exclude   []string
+		missing   string
+		minCount  int
+		minCounts map[string]int
+		wantFiles []string
+		wantErr   []string
+	}{
+		{
+			name:      "all patterns match",
+			patterns:  []string{"*.tar.gz", "README.md"},
+			missing:   "fail",
+			minCount:  1,
+			wantFiles: []string{"app-amd64.tar.gz", "app-arm64.tar.gz", "README.md"},
+		},
+		{
+			name:      "ignore missing files",
+			patterns:  []string{"*.tar.gz", "*.zip"},
+			missing:   "ignore",
+			minCount:  1,
+			wantFiles: []string{"app-amd64.tar.gz", "app-arm64.tar.gz"},
+		},
+		{
+			name:      "warn on missing files",
+			patterns:  []string{"*.tar.gz", "*.zip"},
+			missing:   "warn",
+			minCount:  1,
+			wantFiles: []string{"app-amd64.tar.gz", "app-arm64.tar.gz"},
+		},
+		{
+			name:      "recursive patterns",
+			patterns:  []string{"**/app*"},
+			exclude:   []string{"*.sig.tmp", "linux/arm/**"},
+			missing:   "fail",
+			minCount:  1,
+			wantFiles: []string{"app-amd64.tar.gz", "app-arm64.tar.gz", "linux/app.tar.gz"},
+		},
+		{
+			name:      "remove duplicates",
+			patterns:  []string{"linux/**/*.tar.gz", "**/app.tar.gz"},
+			missing:   "fail",
+			minCount:  1,
+			wantFiles: []string{"linux/app.tar.gz", "linux/arm/app.tar.gz"},
+		},
+		{
+			name:     "fail on missing files",
+			patterns: []string{"*.tar.gz", "*.zip", "CHANGELOG.md"},
+			missing:  "fail",
+			minCount: 1,
+			wantErr:  []string{`*.zip" (0 of 1)`, `CHANGELOG.md" (0 of 1)`},
+		},
+		{
+			name:     "fail on less files than expected",
+			patterns: []string{"*.tar.gz"},
+			missing:  "fail",
+			minCount: 3,
+			wantErr:  []string{`*.tar.gz" (2 of 3)`},
+		},
+		{
+			name:      "pattern min count",
+			patterns:  []string{"*.tar.gz", "*.zip"},
+			missing:   "fail",
+			minCount:  1,
+			minCounts: map[string]int{"*.tar.gz": 2, "./*.zip": 0},
+			wantFiles: []string{"app-amd64.tar.gz", "app-arm64.tar.gz"},
+		},
+		{
+			name:      "fail on less files than pattern min count",
+			patterns:  []string{"*.tar.gz", "README.md"},
+			missing:   "fail",
+			minCount:  3,
+			minCounts: map[string]int{"README.md": 1},
+			wantErr:   []string{`*.tar.gz" (2 of 3)`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exclude := make([]string, 0, len(tt.exclude))
+			for _, pattern := range tt.exclude {
+				if strings.Contains(pattern, "/") {
+					pattern = filepath.ToSlash(filepath.Join(dir, pattern))
+				}
+
+				exclude = append(exclude, pattern)
+			}
+
+			minCounts := make([]string, 0, len(tt.minCounts))
+			for pattern, count := range tt.minCounts {
+				minCounts = append(minCounts, fmt.Sprintf("%s:%d", filepath.Join(dir, pattern), count))
+			}
+
+			parsed, err := parseFilesMinCounts(minCounts)
+			require.NoError(t, err)
+
+			p := newTestPlugin(&Settings{
+				FilesExclude:  exclude,
+				FilesMissing:  tt.missing,
+				FilesMinCount: tt.minCount,
+			})
+			p.Settings.filesMinCounts = parsed
+
+			mappings := make([]fileMapping, 0, len(tt.patterns))
+			for _, pattern := range tt.patterns {
+				mappings = append(mappings, fileMapping{pattern: filepath.Join(dir, pattern)})
+			}
+
+			files, err := p.globFiles(mappings)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, ErrFilesMissing)
+
+				for _, want := range tt.wantErr {
+					assert.ErrorContains(t, err, want)
+				}
+
+				return
+			}
+
+			want := make([]string, 0, len(tt.wantFiles))
+			for _, file := range tt.wantFiles {
+				want = append(want, filepath.Join(dir, file))
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, want, files)
+		})
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	tests := []struct {
+		name         string
+		entries      []string
+		wantMappings []fileMapping
+		wantErr      error
+	}{
+		{
+			name:         "patterns only",
+			entries:      []string{"dist/*", "README.md"},
+			wantMappings: []fileMapping{{pattern: "dist/*"}, {pattern: "README.md"}},
+		},
+		{
+			name:    "file mapping",
+			entries: []string{"build/out.bin => app-{{ .Tag }}-linux-amd64", "README.md"},
+			wantMappings: []fileMapping{
+				{pattern: "build/out.bin", name: "app-{{ .Tag }}-linux-amd64"},
+				{pattern: "README.md"},
+			},
+		},
+		{
+			name:    "missing name",
+			entries: []string{"build/out.bin=>"},
+			wantErr: ErrFileMappingInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappings, err := parseFiles(tt.entries)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMappings, mappings)
+		})
+	}
+}
+
+func TestParseFilesMinCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    map[string]int
+		wantErr error
+	}{
+		{
+			name:    "patterns",
+			entries: []string{"dist/*.tar.gz:4", " ./*.sig : 0 ", "C:/dist/*:2"},
+			want:    map[string]int{"dist/*.tar.gz": 4, "*.sig": 0, "C:/dist/*": 2},
+		},
+		{
+			name:    "missing count",
+			entries: []string{"dist/*.tar.gz"},
+			wantErr: ErrFilesMinCountInvalid,
+		},
+		{
+			name:    "invalid count",
+			entries: []string{"dist/*.tar.gz:many"},
+			wantErr: ErrFilesMinCountInvalid,
+		},
+		{
+			name:    "missing pattern",
+			entries: []string{":1"},
+			wantErr: ErrFilesMinCountInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts, err := parseFilesMinCounts(tt.entries)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, counts)
+		})
+	}
+}
+
+func TestGlobFilesUnknownMinCount(t *testing.T) {
+	p := newTestPlugin(&Settings{
+		FilesMissing:   "fail",
+		filesMinCounts: map[string]int{"dist/*.zip": 1},
+	})
+
+	_, err := p.globFiles([]fileMapping{{pattern: "dist/*.tar.gz"}})
+	assert.ErrorIs(t, err, ErrFilesMinCountInvalid)
+	assert.ErrorContains(t, err, `"dist/*.zip" is not in files`)
+}
+
+func TestFileMappingNames(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	for _, name := range []string{"build/out.bin", "build/out.txt", "dist/app.tar.gz"} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(name), 0o700))
+		assert.NoError(t, os.WriteFile(name, []byte("hello"), 0o600))
+	}
+
+	tests := []struct {
+		name    string
+		entries []string
+		want    []gitea.Asset
+	}{
+		{
+			name:    "relative prefix",
+			entries: []string{"./build/out.bin=>app-{{ .Tag }}"},
+			want:    []gitea.Asset{{Path: "build/out.bin", Name: "app-v1.2.3"}},
+		},
+		{
+			name:    "duplicate separator",
+			entries: []string{"build//out.bin=>app-{{ .Tag }}"},
+			want:    []gitea.Asset{{Path: "build/out.bin", Name: "app-v1.2.3"}},
+		},
+		{
+			name:    "first mapping wins",
+			entries: []string{"build/*", "./build/*.bin=>app", "build/out.*=>other", "dist/*=>{{ .Base }}.bak"},
+			want: []gitea.Asset{
+				{Path: "build/out.bin", Name: "app"},
+				{Path: "build/out.txt", Name: "other"},
+				{Path: "dist/app.tar.gz", Name: "app.tar.gz.bak"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(&Settings{
+				Tag:          "v1.2.3",
+				FilesMissing: "fail",
+			})
+
+			mappings, err := parseFiles(tt.entries)
+			require.NoError(t, err)
+
+			files, err := p.globFiles(mappings)
+			require.NoError(t, err)
+
+			assets, err := p.newAssets(files)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, assets)
+		})
+	}
+}
+
+func TestExecuteAtomic(t *testing.T) {
+	tests := []struct {
+		name           string
+		updateExisting string
+		existing       *gitea_sdk.Release
+		wantDraft      bool
+	}{
+		{
+			name:           "publish created release",
+			updateExisting: "off",
+		},
+		{
+			name:           "publish draft of failed run",
+			updateExisting: "off",
+			existing:       &gitea_sdk.Release{ID: 1, TagName: "v1.0.0", Title: "v1.0.0", IsDraft: true},
+		},
+		{
+			name:           "publish draft of failed run with update",
+			updateExisting: "merge",
+			existing:       &gitea_sdk.Release{ID: 1, TagName: "v1.0.0", Title: "v1.0.0", IsDraft: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeGitea(t, tt.existing)
+			p := newExecutePlugin(t, server.URL, &Settings{
+				Tag:            "v1.0.0",
+				Title:          "v1.0.0",
+				Atomic:         true,
+				UpdateExisting: tt.updateExisting,
+			})
+
+			require.NoError(t, p.Execute())
+
+			release := server.release("v1.0.0")
+			require.NotNil(t, release)
+			assert.Equal(t, tt.wantDraft, release.IsDraft)
+		})
+	}
+}
+
+func TestExecuteTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings *Settings
+		want     string
+	}{
+		{
+			name:     "explicit title",
+			settings: &Settings{Tag: "v1.0.0", Title: "Release"},
+			want:     "Release",
+		},
+		{
+			name:     "tag as fallback",
+			settings: &Settings{Tag: "v1.0.0"},
+			want:     "v1.0.0",
+		},
+		{
+			name:     "tag as fallback for empty template",
+			settings: &Settings{Tag: "v1.0.0", Title: "{{ with .Env.MISSING }}{{ . }}{{ end }}", Template: true},
+			want:     "v1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeGitea(t)
+			p := newExecutePlugin(t, server.URL, tt.settings)
+
+			require.NoError(t, p.Execute())
+
+			release := server.release("v1.0.0")
+			require.NotNil(t, release)
+			assert.Equal(t, tt.want, release.Title)
+		})
+	}
+
+	t.Run("push event with explicit tag", func(t *testing.T) {
+		server := newFakeGitea(t)
+		p := newExecutePlugin(t, server.URL, &Settings{Event: "push", Events: []string{"push"}, Tag: "v2.0.0"})
+
+		require.NoError(t, p.Validate())
+		require.NoError(t, p.Execute())
+
+		release := server.release("v2.0.0")
+		require.NotNil(t, release)
+		assert.Equal(t, "v2.0.0", release.Title)
+	})
+}
+
+// newExecutePlugin creates a plugin with the defaults of the flags that uses the given Gitea URL.
+func newExecutePlugin(t *testing.T, giteaURL string, settings *Settings) *Plugin {
+	t.Helper()
+
+	baseURL, err := url.Parse(giteaURL + "/")
+	require.NoError(t, err)
+
+	settings.baseURL = baseURL
+	settings.FileExists = "overwrite"
+	settings.UploadConcurrency = 1
+	settings.NoteSource = "note"
+	settings.ChangelogMissing = "warn"
+	settings.FilesMissing = "fail"
+	settings.ChecksumFormat = "combined"
+	settings.ChecksumStyle = "gnu"
+
+	if settings.UpdateExisting == "" {
+		settings.UpdateExisting = "off"
+	}
+
+	p := newTestPlugin(settings)
+	p.Metadata.Repository.Owner = "octocat"
+	p.Metadata.Repository.Name = "hello"
+
+	return p
+}
+
+// fakeGitea is a minimal Gitea API server that stores releases in memory.
+type fakeGitea struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	releases []*gitea_sdk.Release
+}
+
+func newFakeGitea(t *testing.T, releases ...*gitea_sdk.Release) *fakeGitea {
+	t.Helper()
+
+	f := &fakeGitea{}
+
+	for _, release := range releases {
+		if release != nil {
+			f.releases = append(f.releases, release)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", func(w http.ResponseWriter, _ *http.Request) {
+		respondJSON(w, map[string]string{"version": "1.22.0"})
+	})
+	// Serves both GET releases/tags/{tag} and GET releases/{id}/assets, the patterns would conflict otherwise.
+	mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}/releases/{segment}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("name") == "assets" {
+			respondJSON(w, []*gitea_sdk.Attachment{})
+
+			return
+		}
+
+		if release := f.release(r.PathValue("name")); r.PathValue("segment") == "tags" && release != nil {
+			respondJSON(w, release)
+
+			return
+		}
+
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("POST /api/v1/repos/{owner}/{repo}/releases", func(w http.ResponseWriter, r *http.Request) {
+		var opt gitea_sdk.CreateReleaseOption
+
+		if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		f.mu.Lock()
+		release := &gitea_sdk.Release{
+			ID:           int64(len(f.releases) + 1),
+			TagName:      opt.TagName,
+			Target:       opt.Target,
+			Title:        opt.Title,
+			Note:         opt.Note,
+			IsDraft:      opt.IsDraft,
+			IsPrerelease: opt.IsPrerelease,
+		}
+		f.releases = append(f.releases, release)
+		f.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		respondJSON(w, release)
+	})
+	mux.HandleFunc("PATCH /api/v1/repos/{owner}/{repo}/releases/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var opt gitea_sdk.EditReleaseOption
+
+		if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		for _, release := range f.releases {
+			if release.ID != id {
+				continue
+			}
+
+			if opt.Title != "" {
+				release.Title = opt.Title
+			}
+
+			if opt.Note != "" {
+				release.Note = opt.Note
+			}
+
+			if opt.IsDraft != nil {
+				release.IsDraft = *opt.IsDraft
+			}
+
+			if opt.IsPrerelease != nil {
+				release.IsPrerelease = *opt.IsPrerelease
+			}
+
+			respondJSON(w, release)
+
+			return
+		}
+
+		http.NotFound(w, r)
+	})
+
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+// release returns a copy of the release with the given tag or nil if it does not exist.
+func (f *fakeGitea) release(tag string) *gitea_sdk.Release {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, release := range f.releases {
+		if release.TagName == tag {
+			copied := *release
+
+			return &copied
+		}
+	}
+
+	return nil
+}
+
+func respondJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
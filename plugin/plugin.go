@@ -3,7 +3,11 @@ package plugin
 import (
 	"fmt"
 	"net/url"
+	"regexp"
+	"time"
 
+	"github.com/thegeeklab/wp-gitea-release/changelog"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
 	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
 	"github.com/urfave/cli/v3"
 )
@@ -18,18 +22,66 @@ type Plugin struct {
 
 // Settings for the Plugin.
 type Settings struct {
-	APIKey     string
-	FileExists string
-	Checksum   []string
-	Draft      bool
-	PreRelease bool
-	Title      string
-	Note       string
-	CommitRef  string
-	Event      string
+	APIKey            string
+	FilesExclude      []string
+	FilesMissing      string
+	FilesMinCount     int
+	FilesMinCounts    []string
+	AssetName         string
+	FileExists        string
+	UpdateExisting    string
+	UploadConcurrency int
+	Prune             bool
+	PruneKeep         []string
+	PruneDryRun       bool
+	DryRun            bool
+	RetryMaxAttempts  int
+	RetryBackoff      time.Duration
+	Checksum          []string
+	ChecksumFormat    string
+	ChecksumStyle     string
+	ChecksumDir       string
+	ChecksumFile      string
+	SignMethod        string
+	SignKey           string
+	SignPassphrase    string
+	SignAssets        bool
+	CosignKey         string
+	CosignPassphrase  string
+	SBOMFormat        string
+	SBOMFile          string
+	SBOMGoModules     bool
+	Provenance        bool
+	Draft             bool
+	PreRelease        bool
+	Atomic            bool
+	AtomicCleanup     bool
+	Title             string
+	Note              string
+	Template          bool
+	NoteSource        string
+	ChangelogGroups   []string
+	ChangelogExclude  []string
+	NoteFromChangelog string
+	ChangelogHeadings []string
+	ChangelogMissing  string
+	CommitRef         string
+	Event             string
+	Events            []string
+	SkipUnsupported   bool
+	DeployTarget      string
+	Tag               string
+	Target            string
 
-	baseURL *url.URL
-	files   []string
+	baseURL          *url.URL
+	files            []string
+	assets           []gitea.Asset
+	fileNames        map[string]string
+	filesMinCounts   map[string]int
+	signer           Signer
+	cosignSigner     *CosignSigner
+	changelogGroups  []changelog.Group
+	changelogExclude []*regexp.Regexp
 }
 
 func New(e plugin_base.ExecuteFunc, build ...string) *Plugin {
@@ -75,18 +127,115 @@ func Flags(settings *Settings, category string) []cli.Flag {
 		},
 		&cli.StringSliceFlag{
 			Name:     "files",
-			Usage:    "list of files to upload",
+			Usage:    "list of files to upload, supports recursive glob patterns and asset names as pattern=>name",
 			Sources:  cli.EnvVars("PLUGIN_FILES", "GITEA_RELEASE_FILES"),
 			Category: category,
 		},
+		&cli.StringSliceFlag{
+			Name:        "files-exclude",
+			Usage:       "list of glob patterns to exclude from the files to upload",
+			Sources:     cli.EnvVars("PLUGIN_FILES_EXCLUDE", "GITEA_RELEASE_FILES_EXCLUDE"),
+			Destination: &settings.FilesExclude,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "files-missing",
+			Value:       "fail",
+			Usage:       "what to do if a file pattern matches fewer files than its minimum count (ignore, warn, fail)",
+			Sources:     cli.EnvVars("PLUGIN_FILES_MISSING", "GITEA_RELEASE_FILES_MISSING"),
+			Destination: &settings.FilesMissing,
+			Category:    category,
+		},
+		&cli.IntFlag{
+			Name:        "files-min-count",
+			Value:       1,
+			Usage:       "minimum number of files each file pattern has to match, unless overridden by files-min-counts",
+			Sources:     cli.EnvVars("PLUGIN_FILES_MIN_COUNT", "GITEA_RELEASE_FILES_MIN_COUNT"),
+			Destination: &settings.FilesMinCount,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "files-min-counts",
+			Usage:       "minimum number of files of single file patterns in the format '<pattern>:<count>'",
+			Sources:     cli.EnvVars("PLUGIN_FILES_MIN_COUNTS", "GITEA_RELEASE_FILES_MIN_COUNTS"),
+			Destination: &settings.FilesMinCounts,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "asset-name",
+			Usage:       "template for the asset names of uploaded files, defaults to the file name",
+			Sources:     cli.EnvVars("PLUGIN_ASSET_NAME", "GITEA_RELEASE_ASSET_NAME"),
+			Destination: &settings.AssetName,
+			Category:    category,
+		},
 		&cli.StringFlag{
 			Name:        "file-exists",
 			Value:       "overwrite",
-			Usage:       "what to do if file already exist",
+			Usage:       "what to do if file already exist (overwrite, replace-if-changed, fail or skip)",
 			Sources:     cli.EnvVars("PLUGIN_FILE_EXIST", "GITEA_RELEASE_FILE_EXIST"),
 			Destination: &settings.FileExists,
 			Category:    category,
 		},
+		&cli.StringFlag{
+			Name:        "update-existing",
+			Value:       "off",
+			Usage:       "how to update the metadata of an existing release (off, merge, replace)",
+			Sources:     cli.EnvVars("PLUGIN_UPDATE_EXISTING", "GITEA_RELEASE_UPDATE_EXISTING"),
+			Destination: &settings.UpdateExisting,
+			Category:    category,
+		},
+		&cli.IntFlag{
+			Name:        "upload-concurrency",
+			Value:       1,
+			Usage:       "maximum number of parallel file uploads",
+			Sources:     cli.EnvVars("PLUGIN_UPLOAD_CONCURRENCY", "GITEA_RELEASE_UPLOAD_CONCURRENCY"),
+			Destination: &settings.UploadConcurrency,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "prune",
+			Usage:       "delete existing files of the release that are not part of the uploaded files",
+			Sources:     cli.EnvVars("PLUGIN_PRUNE", "GITEA_RELEASE_PRUNE"),
+			Destination: &settings.Prune,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "prune-keep",
+			Usage:       "glob patterns of file names that are never pruned",
+			Sources:     cli.EnvVars("PLUGIN_PRUNE_KEEP", "GITEA_RELEASE_PRUNE_KEEP"),
+			Destination: &settings.PruneKeep,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "prune-dry-run",
+			Usage:       "only list the files that would be pruned",
+			Sources:     cli.EnvVars("PLUGIN_PRUNE_DRY_RUN", "GITEA_RELEASE_PRUNE_DRY_RUN"),
+			Destination: &settings.PruneDryRun,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "print the planned changes without modifying the release",
+			Sources:     cli.EnvVars("PLUGIN_DRY_RUN", "GITEA_RELEASE_DRY_RUN"),
+			Destination: &settings.DryRun,
+			Category:    category,
+		},
+		&cli.IntFlag{
+			Name:        "retry-max-attempts",
+			Value:       gitea.DefaultRetryMaxAttempts,
+			Usage:       "maximum number of attempts for API requests that failed with a transient error",
+			Sources:     cli.EnvVars("PLUGIN_RETRY_MAX_ATTEMPTS", "GITEA_RELEASE_RETRY_MAX_ATTEMPTS"),
+			Destination: &settings.RetryMaxAttempts,
+			Category:    category,
+		},
+		&cli.DurationFlag{
+			Name:        "retry-backoff",
+			Value:       gitea.DefaultRetryBackoff,
+			Usage:       "initial wait time between retries, doubled with every retry",
+			Sources:     cli.EnvVars("PLUGIN_RETRY_BACKOFF", "GITEA_RELEASE_RETRY_BACKOFF"),
+			Destination: &settings.RetryBackoff,
+			Category:    category,
+		},
 		&cli.StringSliceFlag{
 			Name:        "checksum",
 			Usage:       "generate specific checksums",
@@ -94,6 +243,108 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.Checksum,
 			Category:    category,
 		},
+		&cli.StringFlag{
+			Name:        "checksum-format",
+			Value:       "combined",
+			Usage:       "write combined checksum files, per-file checksum files or both (combined, per-file, both)",
+			Sources:     cli.EnvVars("PLUGIN_CHECKSUM_FORMAT", "GITEA_RELEASE_CHECKSUM_FORMAT"),
+			Destination: &settings.ChecksumFormat,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "checksum-style",
+			Value:       "gnu",
+			Usage:       "line format of checksum files (gnu, bsd)",
+			Sources:     cli.EnvVars("PLUGIN_CHECKSUM_STYLE", "GITEA_RELEASE_CHECKSUM_STYLE"),
+			Destination: &settings.ChecksumStyle,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "checksum-dir",
+			Usage:       "directory to write the checksum files to",
+			Sources:     cli.EnvVars("PLUGIN_CHECKSUM_DIR", "GITEA_RELEASE_CHECKSUM_DIR"),
+			Destination: &settings.ChecksumDir,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "checksum-file",
+			Usage:       "name of the combined checksum files, {method} and {METHOD} are replaced by the hash method",
+			DefaultText: "sum file name of the hash method, e.g. sha256sum.txt",
+			Sources:     cli.EnvVars("PLUGIN_CHECKSUM_FILE", "GITEA_RELEASE_CHECKSUM_FILE"),
+			Destination: &settings.ChecksumFile,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "sign-method",
+			Usage:       "sign the checksum files with a detached signature (gpg, minisign, signify)",
+			Sources:     cli.EnvVars("PLUGIN_SIGN_METHOD", "GITEA_RELEASE_SIGN_METHOD"),
+			Destination: &settings.SignMethod,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "sign-key",
+			Usage:       "file or string with the armored GPG, minisign or signify secret key",
+			Sources:     cli.EnvVars("PLUGIN_SIGN_KEY", "GITEA_RELEASE_SIGN_KEY"),
+			Destination: &settings.SignKey,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "sign-passphrase",
+			Usage:       "passphrase of the gpg or minisign secret key, encrypted signify keys are not supported",
+			Sources:     cli.EnvVars("PLUGIN_SIGN_PASSPHRASE", "GITEA_RELEASE_SIGN_PASSPHRASE"),
+			Destination: &settings.SignPassphrase,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "sign-assets",
+			Usage:       "sign all assets in addition to the checksum files, signify is limited to files up to 512 MiB",
+			Sources:     cli.EnvVars("PLUGIN_SIGN_ASSETS", "GITEA_RELEASE_SIGN_ASSETS"),
+			Destination: &settings.SignAssets,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "cosign-key",
+			Usage:       "file or string with the cosign private key to write sigstore bundles for all assets",
+			Sources:     cli.EnvVars("PLUGIN_COSIGN_KEY", "GITEA_RELEASE_COSIGN_KEY"),
+			Destination: &settings.CosignKey,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "cosign-passphrase",
+			Usage:       "passphrase of the cosign private key",
+			Sources:     cli.EnvVars("PLUGIN_COSIGN_PASSPHRASE", "GITEA_RELEASE_COSIGN_PASSPHRASE", "COSIGN_PASSWORD"),
+			Destination: &settings.CosignPassphrase,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "sbom-format",
+			Usage:       "generate an SBOM of the uploaded files (cyclonedx, spdx)",
+			Sources:     cli.EnvVars("PLUGIN_SBOM_FORMAT", "GITEA_RELEASE_SBOM_FORMAT"),
+			Destination: &settings.SBOMFormat,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "sbom-file",
+			Usage:       "path of the SBOM file",
+			Sources:     cli.EnvVars("PLUGIN_SBOM_FILE", "GITEA_RELEASE_SBOM_FILE"),
+			Destination: &settings.SBOMFile,
+			DefaultText: "sbom.cdx.json or sbom.spdx.json",
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "sbom-go-modules",
+			Usage:       "add the module dependencies of Go binaries to the SBOM",
+			Sources:     cli.EnvVars("PLUGIN_SBOM_GO_MODULES", "GITEA_RELEASE_SBOM_GO_MODULES"),
+			Destination: &settings.SBOMGoModules,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "provenance",
+			Usage:       "write an in-toto SLSA provenance attestation for the uploaded files",
+			Sources:     cli.EnvVars("PLUGIN_PROVENANCE", "GITEA_RELEASE_PROVENANCE"),
+			Destination: &settings.Provenance,
+			Category:    category,
+		},
 		&cli.BoolFlag{
 			Name:        "draft",
 			Usage:       "create a draft release",
@@ -108,6 +359,20 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.PreRelease,
 			Category:    category,
 		},
+		&cli.BoolFlag{
+			Name:        "atomic",
+			Usage:       "create new releases as draft and publish them after all files are uploaded",
+			Sources:     cli.EnvVars("PLUGIN_ATOMIC", "GITEA_RELEASE_ATOMIC"),
+			Destination: &settings.Atomic,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "atomic-cleanup",
+			Usage:       "delete the draft release created in atomic mode if an upload fails",
+			Sources:     cli.EnvVars("PLUGIN_ATOMIC_CLEANUP", "GITEA_RELEASE_ATOMIC_CLEANUP"),
+			Destination: &settings.AtomicCleanup,
+			Category:    category,
+		},
 		&cli.StringFlag{
 			Name:     "base-url",
 			Usage:    "URL of the Gitea instance",
@@ -122,12 +387,80 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.Note,
 			Category:    category,
 		},
+		&cli.StringFlag{
+			Name:        "note-source",
+			Value:       "note",
+			Usage:       "source of the release notes (note, changelog)",
+			Sources:     cli.EnvVars("PLUGIN_NOTE_SOURCE", "GITEA_RELEASE_NOTE_SOURCE"),
+			Destination: &settings.NoteSource,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "changelog-groups",
+			Value:       []string{"feat:Features", "fix:Bug Fixes", "perf:Performance Improvements"},
+			Usage:       "changelog sections in the format '<type>[|<type>...]:<title>', '*' matches all other types",
+			Sources:     cli.EnvVars("PLUGIN_CHANGELOG_GROUPS", "GITEA_RELEASE_CHANGELOG_GROUPS"),
+			Destination: &settings.ChangelogGroups,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "changelog-exclude",
+			Usage:       "regular expressions to exclude commits from the changelog by subject",
+			Sources:     cli.EnvVars("PLUGIN_CHANGELOG_EXCLUDE", "GITEA_RELEASE_CHANGELOG_EXCLUDE"),
+			Destination: &settings.ChangelogExclude,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "note-from-changelog",
+			Usage:       "path to a changelog file to extract the section of the release tag from",
+			Sources:     cli.EnvVars("PLUGIN_NOTE_FROM_CHANGELOG", "GITEA_RELEASE_NOTE_FROM_CHANGELOG"),
+			Destination: &settings.NoteFromChangelog,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "changelog-headings",
+			Usage:       "regular expressions to match the heading of the release section, '{version}' is replaced by the tag",
+			Sources:     cli.EnvVars("PLUGIN_CHANGELOG_HEADINGS", "GITEA_RELEASE_CHANGELOG_HEADINGS"),
+			Destination: &settings.ChangelogHeadings,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "changelog-missing",
+			Value:       "warn",
+			Usage:       "what to do if the changelog file has no section for the release tag (warn, fail)",
+			Sources:     cli.EnvVars("PLUGIN_CHANGELOG_MISSING", "GITEA_RELEASE_CHANGELOG_MISSING"),
+			Destination: &settings.ChangelogMissing,
+			Category:    category,
+		},
 		&cli.StringFlag{
 			Name:        "title",
 			Usage:       "file or string for the title shown in the Gitea release",
-			Sources:     cli.EnvVars("PLUGIN_TITLE", "GITEA_RELEASE_TITLE", "CI_COMMIT_TAG"),
+			Sources:     cli.EnvVars("PLUGIN_TITLE", "GITEA_RELEASE_TITLE"),
 			Destination: &settings.Title,
-			DefaultText: "$CI_COMMIT_TAG",
+			DefaultText: "release tag",
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "template",
+			Usage:       "render title and note as templates",
+			Sources:     cli.EnvVars("PLUGIN_TEMPLATE", "GITEA_RELEASE_TEMPLATE"),
+			Destination: &settings.Template,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "tag",
+			Usage:       "tag name of the release, supports templates; required for non-tag events",
+			Sources:     cli.EnvVars("PLUGIN_TAG", "GITEA_RELEASE_TAG"),
+			Destination: &settings.Tag,
+			DefaultText: "$CI_COMMIT_REF",
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "target",
+			Usage:       "commit SHA or branch to create the tag from if it does not exist",
+			Sources:     cli.EnvVars("PLUGIN_TARGET", "GITEA_RELEASE_TARGET", "CI_COMMIT_SHA"),
+			Destination: &settings.Target,
+			DefaultText: "$CI_COMMIT_SHA",
 			Category:    category,
 		},
 		&cli.StringFlag{
@@ -139,6 +472,29 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			DefaultText: "$CI_PIPELINE_EVENT",
 			Category:    category,
 		},
+		&cli.StringSliceFlag{
+			Name:        "events",
+			Value:       []string{"tag"},
+			Usage:       "list of pipeline events to create releases for if no explicit tag is set",
+			Sources:     cli.EnvVars("PLUGIN_EVENTS", "GITEA_RELEASE_EVENTS"),
+			Destination: &settings.Events,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "skip-unsupported",
+			Usage:       "exit without error if the pipeline event is not in the list of events",
+			Sources:     cli.EnvVars("PLUGIN_SKIP_UNSUPPORTED", "GITEA_RELEASE_SKIP_UNSUPPORTED"),
+			Destination: &settings.SkipUnsupported,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "deploy-target",
+			Usage:       "deployment target",
+			Sources:     cli.EnvVars("CI_PIPELINE_DEPLOY_TARGET"),
+			Destination: &settings.DeployTarget,
+			DefaultText: "$CI_PIPELINE_DEPLOY_TARGET",
+			Category:    category,
+		},
 		&cli.StringFlag{
 			Name:        "commit-ref",
 			Value:       "refs/heads/main",
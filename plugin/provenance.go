@@ -0,0 +1,204 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/thegeeklab/wp-gitea-release/gitea"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+)
+
+const (
+	// ProvenanceFileName is the conventional name of attestation files covering multiple subjects.
+	ProvenanceFileName = "multiple.intoto.jsonl"
+
+	InTotoStatementType         = "https://in-toto.io/Statement/v0.1"
+	InTotoPayloadType           = "application/vnd.in-toto+json"
+	SLSAProvenancePredicateType = "https://slsa.dev/provenance/v0.2"
+
+	// ProvenanceBuildType describes the invocation parameters and environment written by the plugin.
+	ProvenanceBuildType = "https://github.com/thegeeklab/wp-gitea-release/provenance@v1"
+
+	defaultBuilderID = "https://woodpecker-ci.org"
+)
+
+// ProvenanceOptions configures the attestation written by WriteProvenance.
+type ProvenanceOptions struct {
+	// Path is the file the attestation is written to.
+	Path string
+	// Metadata is the pipeline metadata used for the builder, invocation and materials.
+	Metadata plugin_base.Metadata
+	// Signer signs the statement as DSSE envelope. The plain statement is written if nil.
+	Signer *CosignSigner
+	// Sums caches the checksums of the assets, e.g. calculated by WriteChecksums.
+	Sums AssetSums
+}
+
+type inTotoStatement struct {
+	Type          string          `json:"_type"`
+	Subject       []inTotoSubject `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     slsaProvenance  `json:"predicate"`
+}
+
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type slsaProvenance struct {
+	Builder struct {
+		ID string `json:"id"`
+	} `json:"builder"`
+	BuildType  string         `json:"buildType"`
+	Invocation slsaInvocation `json:"invocation"`
+	Metadata   slsaMetadata   `json:"metadata"`
+	Materials  []slsaMaterial `json:"materials,omitempty"`
+}
+
+type slsaInvocation struct {
+	ConfigSource slsaMaterial      `json:"configSource"`
+	Parameters   map[string]string `json:"parameters,omitempty"`
+	Environment  map[string]string `json:"environment,omitempty"`
+}
+
+type slsaMetadata struct {
+	BuildInvocationID string     `json:"buildInvocationId,omitempty"`
+	BuildStartedOn    *time.Time `json:"buildStartedOn,omitempty"`
+	Completeness      struct {
+		Parameters  bool `json:"parameters"`
+		Environment bool `json:"environment"`
+		Materials   bool `json:"materials"`
+	} `json:"completeness"`
+	Reproducible bool `json:"reproducible"`
+}
+
+type slsaMaterial struct {
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// WriteProvenance writes an in-toto statement with a SLSA provenance predicate for the given
+// assets as a single JSON line. The subjects are the asset names with their SHA256 checksum,
+// which is taken from Sums if cached already. If a signer is configured, the statement is wrapped in a signed DSSE envelope.
+// It returns the written attestation as asset.
+func WriteProvenance(assets []gitea.Asset, opt ProvenanceOptions) (gitea.Asset, error) {
+	statement := inTotoStatement{
+		Type:          InTotoStatementType,
+		Subject:       make([]inTotoSubject, 0, len(assets)),
+		PredicateType: SLSAProvenancePredicateType,
+		Predicate:     newSLSAProvenance(opt.Metadata),
+	}
+
+	if opt.Sums == nil {
+		opt.Sums = AssetSums{}
+	}
+
+	for _, asset := range assets {
+		sums, err := opt.Sums.Get(asset.Path, []string{"sha256"})
+		if err != nil {
+			return gitea.Asset{}, err
+		}
+
+		statement.Subject = append(statement.Subject, inTotoSubject{
+			Name:   asset.Name,
+			Digest: map[string]string{"sha256": sums[0]},
+		})
+	}
+
+	var line any = statement
+
+	if opt.Signer != nil {
+		payload, err := json.Marshal(statement)
+		if err != nil {
+			return gitea.Asset{}, err
+		}
+
+		if line, err = opt.Signer.signDSSE(InTotoPayloadType, payload); err != nil {
+			return gitea.Asset{}, fmt.Errorf("failed to sign provenance: %w", err)
+		}
+	}
+
+	if err := writeJSON(opt.Path, line, ""); err != nil {
+		return gitea.Asset{}, fmt.Errorf("failed to write provenance: %w", err)
+	}
+
+	return gitea.Asset{Path: opt.Path, Name: filepath.Base(opt.Path)}, nil
+}
+
+// newSLSAProvenance creates the provenance predicate from the pipeline metadata. The
+// repository at the pipeline commit is the config source and the only material.
+func newSLSAProvenance(metadata plugin_base.Metadata) slsaProvenance {
+	var provenance slsaProvenance
+
+	provenance.Builder.ID = metadata.System.Link
+	if provenance.Builder.ID == "" {
+		provenance.Builder.ID = defaultBuilderID
+	}
+
+	provenance.BuildType = ProvenanceBuildType
+
+	source := slsaMaterial{}
+
+	if metadata.Repository.URL != "" {
+		source.URI = "git+" + metadata.Repository.URL
+		if metadata.Curr.Ref != "" {
+			source.URI += "@" + metadata.Curr.Ref
+		}
+	}
+
+	if metadata.Curr.SHA != "" {
+		source.Digest = map[string]string{"sha1": metadata.Curr.SHA}
+	}
+
+	provenance.Invocation.ConfigSource = source
+	provenance.Invocation.Parameters = withoutEmpty(map[string]string{
+		"event":        metadata.Pipeline.Event,
+		"deployTarget": metadata.Pipeline.DeployTarget,
+	})
+	provenance.Invocation.Environment = withoutEmpty(map[string]string{
+		"repository": path.Join(metadata.Repository.Owner, metadata.Repository.Name),
+		"branch":     metadata.Curr.Branch,
+		"pipeline":   formatNumber(metadata.Pipeline.Number),
+		"step":       formatNumber(metadata.Step.Number),
+		"system":     metadata.System.Name,
+		"version":    metadata.System.Version,
+	})
+
+	provenance.Metadata.BuildInvocationID = metadata.Pipeline.URL
+
+	if !metadata.Pipeline.Started.IsZero() {
+		started := metadata.Pipeline.Started.UTC()
+		provenance.Metadata.BuildStartedOn = &started
+	}
+
+	if source.URI != "" {
+		provenance.Materials = []slsaMaterial{source}
+	}
+
+	return provenance
+}
+
+// withoutEmpty removes the entries with empty values from the map.
+func withoutEmpty(m map[string]string) map[string]string {
+	for key, value := range m {
+		if value == "" {
+			delete(m, key)
+		}
+	}
+
+	return m
+}
+
+// formatNumber formats pipeline and step numbers, which are unset if zero.
+func formatNumber(n int64) string {
+	if n == 0 {
+		return ""
+	}
+
+	return strconv.FormatInt(n, 10)
+}
@@ -0,0 +1,150 @@
+package plugin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+)
+
+func TestWriteProvenance(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.tar.gz")
+
+	require.NoError(t, os.WriteFile(file, []byte("hello"), 0o600))
+
+	metadata := plugin_base.Metadata{
+		Repository: plugin_base.Repository{
+			Owner: "octocat",
+			Name:  "hello",
+			URL:   "https://gitea.example.com/octocat/hello",
+		},
+		Curr: plugin_base.Commit{
+			SHA: "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+			Ref: "refs/tags/v1.0.0",
+		},
+		Pipeline: plugin_base.Pipeline{
+			Number:  42,
+			Event:   "tag",
+			URL:     "https://ci.example.com/repos/1/pipeline/42",
+			Started: time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+		},
+		System: plugin_base.System{
+			Name: "woodpecker",
+			Link: "https://ci.example.com",
+		},
+	}
+
+	assets := []gitea.Asset{{Path: file, Name: "app-linux-amd64.tar.gz"}}
+
+	t.Run("statement", func(t *testing.T) {
+		provenance, err := WriteProvenance(assets, ProvenanceOptions{
+			Path:     filepath.Join(dir, ProvenanceFileName),
+			Metadata: metadata,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, ProvenanceFileName, provenance.Name)
+
+		data, err := os.ReadFile(provenance.Path)
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(data), "\n"))
+
+		var statement inTotoStatement
+
+		require.NoError(t, json.Unmarshal(data, &statement))
+
+		assert.Equal(t, InTotoStatementType, statement.Type)
+		assert.Equal(t, SLSAProvenancePredicateType, statement.PredicateType)
+		assert.Equal(t, []inTotoSubject{{
+			Name:   "app-linux-amd64.tar.gz",
+			Digest: map[string]string{"sha256": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		}}, statement.Subject)
+
+		predicate := statement.Predicate
+		source := slsaMaterial{
+			URI:    "git+https://gitea.example.com/octocat/hello@refs/tags/v1.0.0",
+			Digest: map[string]string{"sha1": "6dcb09b5b57875f334f61aebed695e2e4193db5e"},
+		}
+
+		assert.Equal(t, "https://ci.example.com", predicate.Builder.ID)
+		assert.Equal(t, source, predicate.Invocation.ConfigSource)
+		assert.Equal(t, map[string]string{"event": "tag"}, predicate.Invocation.Parameters)
+		assert.Equal(t, map[string]string{
+			"repository": "octocat/hello",
+			"pipeline":   "42",
+			"system":     "woodpecker",
+		}, predicate.Invocation.Environment)
+		assert.Equal(t, "https://ci.example.com/repos/1/pipeline/42", predicate.Metadata.BuildInvocationID)
+		assert.Equal(t, metadata.Pipeline.Started, *predicate.Metadata.BuildStartedOn)
+		assert.Equal(t, []slsaMaterial{source}, predicate.Materials)
+	})
+
+	t.Run("cached checksums", func(t *testing.T) {
+		provenance, err := WriteProvenance(assets, ProvenanceOptions{
+			Path:     filepath.Join(dir, "cached.intoto.jsonl"),
+			Metadata: metadata,
+			Sums:     AssetSums{file: {"sha256": "cached"}},
+		})
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(provenance.Path)
+		require.NoError(t, err)
+
+		var statement inTotoStatement
+
+		require.NoError(t, json.Unmarshal(data, &statement))
+		assert.Equal(t, map[string]string{"sha256": "cached"}, statement.Subject[0].Digest)
+	})
+
+	t.Run("signed envelope", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		der, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+
+		signer, err := NewCosignSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), "")
+		require.NoError(t, err)
+
+		provenance, err := WriteProvenance(assets, ProvenanceOptions{
+			Path:     filepath.Join(dir, "signed.intoto.jsonl"),
+			Metadata: metadata,
+			Signer:   signer,
+		})
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(provenance.Path)
+		require.NoError(t, err)
+
+		var envelope dsseEnvelope
+
+		require.NoError(t, json.Unmarshal(data, &envelope))
+		require.Len(t, envelope.Signatures, 1)
+		assert.Equal(t, InTotoPayloadType, envelope.PayloadType)
+
+		var statement inTotoStatement
+
+		require.NoError(t, json.Unmarshal(envelope.Payload, &statement))
+		assert.Equal(t, "app-linux-amd64.tar.gz", statement.Subject[0].Name)
+
+		pae := fmt.Sprintf("DSSEv1 %d %s %d %s",
+			len(envelope.PayloadType), envelope.PayloadType, len(envelope.Payload), envelope.Payload)
+		digest := sha256.Sum256([]byte(pae))
+
+		assert.True(t, ecdsa.VerifyASN1(&key.PublicKey, digest[:], envelope.Signatures[0].Sig))
+	})
+}
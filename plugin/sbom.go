@@ -0,0 +1,504 @@
+package plugin
+
+import (
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
+)
+
+var ErrSBOMFormatInvalid = errors.New("invalid sbom_format value")
+
+const (
+	SBOMFormatCycloneDX = "cyclonedx"
+	SBOMFormatSPDX      = "spdx"
+
+	sbomToolName     = "wp-gitea-release"
+	sbomSizeProperty = sbomToolName + ":size"
+)
+
+// cycloneDXHashAlgs maps the hash methods to the hash algorithms of CycloneDX 1.5.
+// Hash methods without a matching algorithm are omitted from the SBOM.
+//
+//nolint:gochecknoglobals
+var cycloneDXHashAlgs = map[string]string{
+	"md5":         "MD5",
+	"sha1":        "SHA-1",
+	"sha256":      "SHA-256",
+	"sha384":      "SHA-384",
+	"sha512":      "SHA-512",
+	"sha3-256":    "SHA3-256",
+	"sha3-512":    "SHA3-512",
+	"blake2b":     "BLAKE2b-256",
+	"blake2b-512": "BLAKE2b-512",
+	"blake3":      "BLAKE3",
+}
+
+// spdxHashAlgs maps the hash methods to the checksum algorithms of SPDX 2.3.
+//
+//nolint:gochecknoglobals
+var spdxHashAlgs = map[string]string{
+	"md5":         "MD5",
+	"sha1":        "SHA1",
+	"sha224":      "SHA224",
+	"sha256":      "SHA256",
+	"sha384":      "SHA384",
+	"sha512":      "SHA512",
+	"sha3-256":    "SHA3-256",
+	"sha3-512":    "SHA3-512",
+	"adler32":     "ADLER32",
+	"blake2b":     "BLAKE2b-256",
+	"blake2b-512": "BLAKE2b-512",
+	"blake3":      "BLAKE3",
+}
+
+// SBOMOptions configures the SBOM written by WriteSBOM.
+type SBOMOptions struct {
+	// Format is either "cyclonedx" (CycloneDX 1.5) or "spdx" (SPDX 2.3).
+	Format string
+	// Path is the file the SBOM is written to.
+	Path string
+	// Name and Version describe the release, e.g. the repository and the tag.
+	Name    string
+	Version string
+	// RepositoryURL and Commit describe the source of the release.
+	RepositoryURL string
+	Commit        string
+	// Methods are the hash methods listed for each asset in addition to SHA256.
+	Methods []string
+	// GoModules adds the modules of Go binaries read from the embedded build information.
+	GoModules bool
+	// Created is the creation time of the SBOM. Defaults to the current time.
+	Created time.Time
+	// Sums caches the checksums of the assets, e.g. calculated by WriteChecksums.
+	Sums AssetSums
+}
+
+// sbomAsset holds the information about an asset listed in the SBOM.
+type sbomAsset struct {
+	gitea.Asset
+	size    int64
+	sums    map[string]string
+	modules []goModule
+}
+
+type goModule struct {
+	path    string
+	version string
+}
+
+func (m goModule) purl() string {
+	return fmt.Sprintf("pkg:golang/%s@%s", m.path, m.version)
+}
+
+// WriteSBOM writes an SBOM in JSON format that lists the given assets with their name, size
+// and checksums. Checksums cached in Sums are reused, so assets that were checksummed
+// already are not read again. It returns the written SBOM as asset.
+func WriteSBOM(assets []gitea.Asset, opt SBOMOptions) (gitea.Asset, error) {
+	algs, ok := map[string]map[string]string{
+		SBOMFormatCycloneDX: cycloneDXHashAlgs,
+		SBOMFormatSPDX:      spdxHashAlgs,
+	}[opt.Format]
+	if !ok {
+		return gitea.Asset{}, fmt.Errorf("%w: %q", ErrSBOMFormatInvalid, opt.Format)
+	}
+
+	if opt.Created.IsZero() {
+		opt.Created = time.Now()
+	}
+
+	if opt.Sums == nil {
+		opt.Sums = AssetSums{}
+	}
+
+	methods := []string{"sha256"}
+
+	for _, method := range opt.Methods {
+		if _, ok := algs[method]; ok && !slices.Contains(methods, method) {
+			methods = append(methods, method)
+		}
+	}
+
+	files := make([]sbomAsset, 0, len(assets))
+
+	for _, asset := range assets {
+		file, err := newSBOMAsset(asset, methods, opt)
+		if err != nil {
+			return gitea.Asset{}, err
+		}
+
+		files = append(files, file)
+	}
+
+	var doc any
+
+	switch opt.Format {
+	case SBOMFormatCycloneDX:
+		doc = newCycloneDX(files, methods, opt)
+	case SBOMFormatSPDX:
+		doc = newSPDX(files, methods, opt)
+	}
+
+	if err := writeJSON(opt.Path, doc, "  "); err != nil {
+		return gitea.Asset{}, fmt.Errorf("failed to write SBOM: %w", err)
+	}
+
+	return gitea.Asset{Path: opt.Path, Name: filepath.Base(opt.Path)}, nil
+}
+
+// writeJSON writes the value as JSON with the given indent. Without indent,
+// the value is written as a single line.
+func writeJSON(path string, v any, indent string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", indent)
+
+	if err := encoder.Encode(v); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+func newSBOMAsset(asset gitea.Asset, methods []string, opt SBOMOptions) (sbomAsset, error) {
+	info, err := os.Stat(asset.Path)
+	if err != nil {
+		return sbomAsset{}, err
+	}
+
+	sums, err := opt.Sums.Get(asset.Path, methods)
+	if err != nil {
+		return sbomAsset{}, err
+	}
+
+	file := sbomAsset{Asset: asset, size: info.Size(), sums: make(map[string]string)}
+
+	for i, method := range methods {
+		file.sums[method] = sums[i]
+	}
+
+	if !opt.GoModules {
+		return file, nil
+	}
+
+	// Files without build information are not Go binaries and have no modules.
+	build, err := buildinfo.ReadFile(asset.Path)
+	if err != nil {
+		return file, nil //nolint:nilerr
+	}
+
+	if build.Main.Path != "" {
+		file.modules = append(file.modules, goModule{path: build.Main.Path, version: build.Main.Version})
+	}
+
+	for _, dep := range build.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+
+		file.modules = append(file.modules, goModule{path: dep.Path, version: dep.Version})
+	}
+
+	return file, nil
+}
+
+type cdxBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []cdxComponent `json:"components"`
+	} `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxComponent struct {
+	BOMRef             string                 `json:"bom-ref,omitempty"`
+	Type               string                 `json:"type"`
+	Name               string                 `json:"name"`
+	Version            string                 `json:"version,omitempty"`
+	PURL               string                 `json:"purl,omitempty"`
+	Hashes             []cdxHash              `json:"hashes,omitempty"`
+	ExternalReferences []cdxExternalReference `json:"externalReferences,omitempty"`
+	Pedigree           *cdxPedigree           `json:"pedigree,omitempty"`
+	Properties         []cdxProperty          `json:"properties,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxPedigree struct {
+	Commits []cdxCommit `json:"commits"`
+}
+
+type cdxCommit struct {
+	UID string `json:"uid"`
+	URL string `json:"url,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// newCycloneDX creates a CycloneDX 1.5 BOM. The release is the described component with
+// the repository as VCS reference and the commit as pedigree. Assets are file components,
+// Go modules are library components referenced by the dependencies of the asset.
+func newCycloneDX(files []sbomAsset, methods []string, opt SBOMOptions) cdxBOM {
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Components:   make([]cdxComponent, 0, len(files)),
+	}
+
+	bom.Metadata.Timestamp = opt.Created.UTC().Format(time.RFC3339)
+	bom.Metadata.Tools.Components = []cdxComponent{{Type: "application", Name: sbomToolName}}
+	bom.Metadata.Component = cdxComponent{
+		BOMRef:  "release",
+		Type:    "application",
+		Name:    opt.Name,
+		Version: opt.Version,
+	}
+
+	if opt.RepositoryURL != "" {
+		bom.Metadata.Component.ExternalReferences = []cdxExternalReference{{Type: "vcs", URL: opt.RepositoryURL}}
+	}
+
+	if opt.Commit != "" {
+		bom.Metadata.Component.Pedigree = &cdxPedigree{Commits: []cdxCommit{{UID: opt.Commit, URL: opt.RepositoryURL}}}
+	}
+
+	libraries := make([]cdxComponent, 0)
+	seen := make(map[string]bool)
+
+	for _, file := range files {
+		component := cdxComponent{
+			BOMRef:     "asset:" + file.Name,
+			Type:       "file",
+			Name:       file.Name,
+			Hashes:     make([]cdxHash, 0, len(methods)),
+			Properties: []cdxProperty{{Name: sbomSizeProperty, Value: strconv.FormatInt(file.size, 10)}},
+		}
+
+		for _, method := range methods {
+			component.Hashes = append(component.Hashes, cdxHash{Alg: cycloneDXHashAlgs[method], Content: file.sums[method]})
+		}
+
+		bom.Components = append(bom.Components, component)
+
+		if len(file.modules) == 0 {
+			continue
+		}
+
+		dependency := cdxDependency{Ref: component.BOMRef, DependsOn: make([]string, 0, len(file.modules))}
+
+		for _, module := range file.modules {
+			purl := module.purl()
+			dependency.DependsOn = append(dependency.DependsOn, purl)
+
+			if seen[purl] {
+				continue
+			}
+
+			seen[purl] = true
+
+			libraries = append(libraries, cdxComponent{
+				BOMRef:  purl,
+				Type:    "library",
+				Name:    module.path,
+				Version: module.version,
+				PURL:    purl,
+			})
+		}
+
+		bom.Dependencies = append(bom.Dependencies, dependency)
+	}
+
+	bom.Components = append(bom.Components, libraries...)
+
+	return bom
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	PackageFileName  string            `json:"packageFileName,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// newSPDX creates an SPDX 2.3 document. The source repository at the commit is a package
+// the assets are generated from. Assets are packages described by the document, as SPDX
+// files require a SHA1 checksum. Go modules are packages the assets depend on.
+func newSPDX(files []sbomAsset, methods []string, opt SBOMOptions) spdxDocument {
+	const (
+		documentID = "SPDXRef-DOCUMENT"
+		sourceID   = "SPDXRef-Source"
+	)
+
+	name := strings.TrimSpace(strings.Join([]string{opt.Name, opt.Version}, " "))
+
+	namespace := opt.RepositoryURL
+	if namespace == "" {
+		namespace = "https://spdx.org/spdxdocs"
+	}
+
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            documentID,
+		Name:              name,
+		DocumentNamespace: fmt.Sprintf("%s/sbom/%s", strings.TrimSuffix(namespace, "/"), uuid.NewString()),
+		CreationInfo: spdxCreationInfo{
+			Created:  opt.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + sbomToolName},
+		},
+		Packages:      make([]spdxPackage, 0, len(files)+1),
+		Relationships: make([]spdxRelationship, 0, len(files)),
+	}
+
+	source := spdxPackage{
+		SPDXID:           sourceID,
+		Name:             opt.Name,
+		VersionInfo:      opt.Version,
+		DownloadLocation: "NOASSERTION",
+	}
+
+	if opt.RepositoryURL != "" {
+		source.DownloadLocation = "git+" + opt.RepositoryURL
+		if opt.Commit != "" {
+			source.DownloadLocation += "@" + opt.Commit
+		}
+	}
+
+	doc.Packages = append(doc.Packages, source)
+
+	modules := make([]spdxPackage, 0)
+	moduleIDs := make(map[string]string)
+
+	for i, file := range files {
+		pkg := spdxPackage{
+			SPDXID:           fmt.Sprintf("SPDXRef-Asset-%d", i+1),
+			Name:             file.Name,
+			VersionInfo:      opt.Version,
+			PackageFileName:  file.Name,
+			DownloadLocation: "NOASSERTION",
+			Checksums:        make([]spdxChecksum, 0, len(methods)),
+			Comment:          fmt.Sprintf("size: %d bytes", file.size),
+		}
+
+		for _, method := range methods {
+			pkg.Checksums = append(pkg.Checksums, spdxChecksum{Algorithm: spdxHashAlgs[method], ChecksumValue: file.sums[method]})
+		}
+
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships,
+			spdxRelationship{SPDXElementID: documentID, RelationshipType: "DESCRIBES", RelatedSPDXElement: pkg.SPDXID},
+			spdxRelationship{SPDXElementID: pkg.SPDXID, RelationshipType: "GENERATED_FROM", RelatedSPDXElement: sourceID},
+		)
+
+		for _, module := range file.modules {
+			purl := module.purl()
+
+			id, ok := moduleIDs[purl]
+			if !ok {
+				id = fmt.Sprintf("SPDXRef-Module-%d", len(moduleIDs)+1)
+				moduleIDs[purl] = id
+
+				modules = append(modules, spdxPackage{
+					SPDXID:           id,
+					Name:             module.path,
+					VersionInfo:      module.version,
+					DownloadLocation: "NOASSERTION",
+					ExternalRefs: []spdxExternalRef{{
+						ReferenceCategory: "PACKAGE-MANAGER",
+						ReferenceType:     "purl",
+						ReferenceLocator:  purl,
+					}},
+				})
+			}
+
+			doc.Relationships = append(doc.Relationships,
+				spdxRelationship{SPDXElementID: pkg.SPDXID, RelationshipType: "DEPENDS_ON", RelatedSPDXElement: id},
+			)
+		}
+	}
+
+	doc.Packages = append(doc.Packages, modules...)
+
+	return doc
+}
@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
+)
+
+func TestWriteSBOM(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.tar.gz")
+
+	require.NoError(t, os.WriteFile(file, []byte("hello"), 0o600))
+
+	assets := []gitea.Asset{{Path: file, Name: "app-linux-amd64.tar.gz"}}
+	opt := SBOMOptions{
+		Name:          "octocat/hello",
+		Version:       "v1.0.0",
+		RepositoryURL: "https://gitea.example.com/octocat/hello",
+		Commit:        "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+		Methods:       []string{"sha256", "md5", "crc32"},
+		Created:       time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+	}
+
+	const (
+		sha256Sum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+		md5Sum    = "5d41402abc4b2a76b9719d911017c592"
+	)
+
+	t.Run("cyclonedx", func(t *testing.T) {
+		opt := opt
+		opt.Format = SBOMFormatCycloneDX
+		opt.Path = filepath.Join(dir, "sbom.cdx.json")
+
+		sbom, err := WriteSBOM(assets, opt)
+		require.NoError(t, err)
+		assert.Equal(t, "sbom.cdx.json", sbom.Name)
+
+		var bom cdxBOM
+
+		readJSON(t, sbom.Path, &bom)
+
+		assert.Equal(t, "CycloneDX", bom.BOMFormat)
+		assert.Regexp(t, "^urn:uuid:", bom.SerialNumber)
+		assert.Equal(t, "2024-01-31T12:00:00Z", bom.Metadata.Timestamp)
+		assert.Equal(t, "octocat/hello", bom.Metadata.Component.Name)
+		assert.Equal(t, "v1.0.0", bom.Metadata.Component.Version)
+		assert.Equal(t, opt.RepositoryURL, bom.Metadata.Component.ExternalReferences[0].URL)
+		assert.Equal(t, opt.Commit, bom.Metadata.Component.Pedigree.Commits[0].UID)
+
+		require.Len(t, bom.Components, 1)
+		assert.Equal(t, "app-linux-amd64.tar.gz", bom.Components[0].Name)
+		assert.Equal(t, []cdxHash{
+			{Alg: "SHA-256", Content: sha256Sum},
+			{Alg: "MD5", Content: md5Sum},
+		}, bom.Components[0].Hashes)
+		assert.Equal(t, []cdxProperty{{Name: sbomSizeProperty, Value: "5"}}, bom.Components[0].Properties)
+		assert.Empty(t, bom.Dependencies)
+	})
+
+	t.Run("spdx", func(t *testing.T) {
+		opt := opt
+		opt.Format = SBOMFormatSPDX
+		opt.Path = filepath.Join(dir, "sbom.spdx.json")
+
+		sbom, err := WriteSBOM(assets, opt)
+		require.NoError(t, err)
+
+		var doc spdxDocument
+
+		readJSON(t, sbom.Path, &doc)
+
+		assert.Equal(t, "SPDX-2.3", doc.SPDXVersion)
+		assert.Equal(t, "octocat/hello v1.0.0", doc.Name)
+		assert.Regexp(t, "^https://gitea.example.com/octocat/hello/sbom/", doc.DocumentNamespace)
+
+		require.Len(t, doc.Packages, 2)
+		assert.Equal(t, "git+"+opt.RepositoryURL+"@"+opt.Commit, doc.Packages[0].DownloadLocation)
+		assert.Equal(t, "app-linux-amd64.tar.gz", doc.Packages[1].Name)
+		assert.Equal(t, "size: 5 bytes", doc.Packages[1].Comment)
+		assert.Equal(t, []spdxChecksum{
+			{Algorithm: "SHA256", ChecksumValue: sha256Sum},
+			{Algorithm: "MD5", ChecksumValue: md5Sum},
+		}, doc.Packages[1].Checksums)
+		assert.Equal(t, []spdxRelationship{
+			{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-Asset-1"},
+			{SPDXElementID: "SPDXRef-Asset-1", RelationshipType: "GENERATED_FROM", RelatedSPDXElement: "SPDXRef-Source"},
+		}, doc.Relationships)
+	})
+
+	t.Run("cached checksums", func(t *testing.T) {
+		opt := opt
+		opt.Format = SBOMFormatCycloneDX
+		opt.Path = filepath.Join(dir, "cached.cdx.json")
+		opt.Methods = nil
+		opt.Sums = AssetSums{file: {"sha256": "cached"}}
+
+		sbom, err := WriteSBOM(assets, opt)
+		require.NoError(t, err)
+
+		var bom cdxBOM
+
+		readJSON(t, sbom.Path, &bom)
+
+		assert.Equal(t, []cdxHash{{Alg: "SHA-256", Content: "cached"}}, bom.Components[0].Hashes)
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		opt := opt
+		opt.Format = "swid"
+
+		_, err := WriteSBOM(assets, opt)
+		assert.ErrorIs(t, err, ErrSBOMFormatInvalid)
+	})
+}
+
+// TestWriteSBOMGoModules uses the test binary as Go binary with embedded build information.
+func TestWriteSBOMGoModules(t *testing.T) {
+	binary, err := os.Executable()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+
+	require.NoError(t, os.WriteFile(file, []byte("hello"), 0o600))
+
+	sbom, err := WriteSBOM([]gitea.Asset{{Path: binary, Name: "app"}, {Path: file, Name: "notes.txt"}}, SBOMOptions{
+		Format:    SBOMFormatCycloneDX,
+		Path:      filepath.Join(dir, "sbom.cdx.json"),
+		GoModules: true,
+	})
+	require.NoError(t, err)
+
+	var bom cdxBOM
+
+	readJSON(t, sbom.Path, &bom)
+
+	require.Len(t, bom.Dependencies, 1)
+	assert.Equal(t, "asset:app", bom.Dependencies[0].Ref)
+
+	assert.True(t, slices.ContainsFunc(bom.Dependencies[0].DependsOn, func(ref string) bool {
+		return strings.HasPrefix(ref, "pkg:golang/github.com/stretchr/testify@")
+	}))
+	assert.True(t, slices.ContainsFunc(bom.Components, func(c cdxComponent) bool {
+		return c.Type == "library" && c.Name == "github.com/stretchr/testify"
+	}))
+}
+
+func readJSON(t *testing.T, path string, v any) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, v))
+}
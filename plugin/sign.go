@@ -0,0 +1,251 @@
+package plugin
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"aead.dev/minisign"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
+)
+
+var (
+	ErrSignMethodInvalid = errors.New("invalid sign_method value")
+	ErrSignKeyRequired   = errors.New("sign_key required")
+	ErrSignKeyInvalid    = errors.New("invalid sign_key")
+	ErrSignFileTooLarge  = errors.New("file too large to sign")
+)
+
+const (
+	SignMethodGPG      = "gpg"
+	SignMethodMinisign = "minisign"
+	SignMethodSignify  = "signify"
+
+	signifyCommentPrefix = "untrusted comment: "
+	signifyKeyNumSize    = 8
+	signifyChecksumSize  = 8
+	signifySaltSize      = 16
+	// "Ed" + "BK" + rounds + salt + checksum + keynum + secret key.
+	signifySecretKeySize = 2 + 2 + 4 + signifySaltSize + signifyChecksumSize + signifyKeyNumSize + ed25519.PrivateKeySize
+	// signifyMaxSize limits the files signed with signify, which are read into memory.
+	signifyMaxSize = 512 << 20
+)
+
+// Signer creates detached signatures.
+type Signer interface {
+	// Sign reads the content from r and writes the detached signature to w.
+	Sign(w io.Writer, r io.Reader) error
+	// Ext returns the file extension of the signature files, e.g. ".asc".
+	Ext() string
+}
+
+// NewSigner creates a Signer for the given method from a secret key. Supported methods are
+// "gpg" for armored OpenPGP keys, "minisign" for encrypted minisign keys and "signify" for
+// signify keys without passphrase (signify -G -n). The passphrase decrypts the key if required.
+func NewSigner(method string, key []byte, passphrase string) (Signer, error) {
+	if len(bytes.TrimSpace(key)) == 0 {
+		return nil, ErrSignKeyRequired
+	}
+
+	switch method {
+	case SignMethodGPG:
+		return newGPGSigner(key, passphrase)
+	case SignMethodMinisign:
+		return newMinisignSigner(key, passphrase)
+	case SignMethodSignify:
+		return newSignifySigner(key)
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrSignMethodInvalid, method)
+}
+
+// SignFiles writes a detached signature next to each of the given assets. The signature
+// files are named after the file and the asset with the extension of the signer appended.
+// It returns the written signature files.
+func SignFiles(signer Signer, assets []gitea.Asset) ([]gitea.Asset, error) {
+	signatures := make([]gitea.Asset, 0, len(assets))
+
+	for _, asset := range assets {
+		signature := gitea.Asset{
+			Path: asset.Path + signer.Ext(),
+			Name: asset.Name + signer.Ext(),
+		}
+
+		if err := signFile(signer, asset.Path, signature.Path); err != nil {
+			return nil, fmt.Errorf("failed to sign %s: %w", asset.Path, err)
+		}
+
+		signatures = append(signatures, signature)
+	}
+
+	return signatures, nil
+}
+
+func signFile(signer Signer, path, signature string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(signature)
+	if err != nil {
+		return err
+	}
+
+	if err := signer.Sign(out, in); err != nil {
+		out.Close()
+
+		return err
+	}
+
+	return out.Close()
+}
+
+type gpgSigner struct {
+	entity *openpgp.Entity
+}
+
+func newGPGSigner(key []byte, passphrase string) (*gpgSigner, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSignKeyInvalid, err)
+	}
+
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			continue
+		}
+
+		if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSignKeyInvalid, err)
+		}
+
+		return &gpgSigner{entity: entity}, nil
+	}
+
+	return nil, fmt.Errorf("%w: no private key found", ErrSignKeyInvalid)
+}
+
+func (s *gpgSigner) Sign(w io.Writer, r io.Reader) error {
+	return openpgp.ArmoredDetachSign(w, s.entity, r, nil)
+}
+
+func (s *gpgSigner) Ext() string {
+	return ".asc"
+}
+
+type minisignSigner struct {
+	key minisign.PrivateKey
+}
+
+func newMinisignSigner(key []byte, passphrase string) (*minisignSigner, error) {
+	privateKey, err := minisign.DecryptKey(passphrase, key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSignKeyInvalid, err)
+	}
+
+	return &minisignSigner{key: privateKey}, nil
+}
+
+// Sign creates a prehashed signature, so the content is streamed and not read into memory.
+func (s *minisignSigner) Sign(w io.Writer, r io.Reader) error {
+	reader := minisign.NewReader(r)
+
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return err
+	}
+
+	_, err := w.Write(reader.Sign(s.key))
+
+	return err
+}
+
+func (s *minisignSigner) Ext() string {
+	return ".minisig"
+}
+
+type signifySigner struct {
+	keyNum  []byte
+	key     ed25519.PrivateKey
+	maxSize int64
+}
+
+func newSignifySigner(key []byte) (*signifySigner, error) {
+	data, err := decodeSignify(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) != signifySecretKeySize || string(data[0:2]) != "Ed" || string(data[2:4]) != "BK" {
+		return nil, fmt.Errorf("%w: not a signify secret key", ErrSignKeyInvalid)
+	}
+
+	if rounds := binary.BigEndian.Uint32(data[4:8]); rounds != 0 {
+		return nil, fmt.Errorf("%w: encrypted signify keys are not supported", ErrSignKeyInvalid)
+	}
+
+	offset := 8 + signifySaltSize
+	checksum := data[offset : offset+signifyChecksumSize]
+	keyNum := data[offset+signifyChecksumSize : offset+signifyChecksumSize+signifyKeyNumSize]
+	privateKey := ed25519.PrivateKey(data[len(data)-ed25519.PrivateKeySize:])
+
+	sum := sha512.Sum512(privateKey)
+	if subtle.ConstantTimeCompare(sum[:signifyChecksumSize], checksum) != 1 {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrSignKeyInvalid)
+	}
+
+	return &signifySigner{keyNum: keyNum, key: privateKey, maxSize: signifyMaxSize}, nil
+}
+
+// Sign creates a signify signature. Signify signs the plain content, so the whole
+// content is read into memory and content larger than 512 MiB is rejected.
+func (s *signifySigner) Sign(w io.Writer, r io.Reader) error {
+	content, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return err
+	}
+
+	if int64(len(content)) > s.maxSize {
+		return fmt.Errorf("%w: signify supports files up to %d bytes", ErrSignFileTooLarge, s.maxSize)
+	}
+
+	data := make([]byte, 0, 2+signifyKeyNumSize+ed25519.SignatureSize)
+	data = append(data, "Ed"...)
+	data = append(data, s.keyNum...)
+	data = append(data, ed25519.Sign(s.key, content)...)
+
+	_, err = fmt.Fprintf(w, "%sverify with signify public key\n%s\n",
+		signifyCommentPrefix, base64.StdEncoding.EncodeToString(data))
+
+	return err
+}
+
+func (s *signifySigner) Ext() string {
+	return ".sig"
+}
+
+// decodeSignify decodes the base64 data line of a signify file with an optional comment line.
+func decodeSignify(content []byte) ([]byte, error) {
+	text := strings.TrimSpace(string(content))
+
+	if strings.HasPrefix(text, signifyCommentPrefix) {
+		_, text, _ = strings.Cut(text, "\n")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSignKeyInvalid, err)
+	}
+
+	return data, nil
+}
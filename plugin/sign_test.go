@@ -0,0 +1,288 @@
+package plugin
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aead.dev/minisign"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/scrypt"
+)
+
+const testPassphrase = "secret"
+
+func TestSignFiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		newKey     func(t *testing.T) ([]byte, func(content, signature []byte) bool)
+		passphrase string
+		wantExt    string
+	}{
+		{
+			name:       "gpg",
+			method:     SignMethodGPG,
+			newKey:     gpgTestKey,
+			passphrase: testPassphrase,
+			wantExt:    ".asc",
+		},
+		{
+			name:       "minisign",
+			method:     SignMethodMinisign,
+			newKey:     minisignTestKey,
+			passphrase: testPassphrase,
+			wantExt:    ".minisig",
+		},
+		{
+			name:    "signify",
+			method:  SignMethodSignify,
+			newKey:  signifyTestKey,
+			wantExt: ".sig",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, verify := tt.newKey(t)
+
+			signer, err := NewSigner(tt.method, key, tt.passphrase)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantExt, signer.Ext())
+
+			dir := t.TempDir()
+			file := filepath.Join(dir, "sha256sum.txt")
+			content := []byte("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  app\n")
+
+			require.NoError(t, os.WriteFile(file, content, 0o600))
+
+			signatures, err := SignFiles(signer, []gitea.Asset{{Path: file, Name: "checksums.txt"}})
+			require.NoError(t, err)
+			require.Len(t, signatures, 1)
+			assert.Equal(t, file+tt.wantExt, signatures[0].Path)
+			assert.Equal(t, "checksums.txt"+tt.wantExt, signatures[0].Name)
+
+			signature, err := os.ReadFile(signatures[0].Path)
+			require.NoError(t, err)
+			assert.True(t, verify(content, signature))
+			assert.False(t, verify([]byte("tampered"), signature))
+		})
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	gpgKey, _ := gpgTestKey(t)
+	minisignKey, _ := minisignTestKey(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		key        []byte
+		passphrase string
+		wantErr    error
+	}{
+		{
+			name:    "missing key",
+			method:  SignMethodGPG,
+			wantErr: ErrSignKeyRequired,
+		},
+		{
+			name:    "invalid method",
+			method:  "pgp",
+			key:     gpgKey,
+			wantErr: ErrSignMethodInvalid,
+		},
+		{
+			name:       "wrong gpg passphrase",
+			method:     SignMethodGPG,
+			key:        gpgKey,
+			passphrase: "wrong",
+			wantErr:    ErrSignKeyInvalid,
+		},
+		{
+			name:       "wrong minisign passphrase",
+			method:     SignMethodMinisign,
+			key:        minisignKey,
+			passphrase: "wrong",
+			wantErr:    ErrSignKeyInvalid,
+		},
+		{
+			name:    "gpg key for signify",
+			method:  SignMethodSignify,
+			key:     gpgKey,
+			wantErr: ErrSignKeyInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSigner(tt.method, tt.key, tt.passphrase)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSignifyMaxSize(t *testing.T) {
+	key, _ := signifyTestKey(t)
+
+	signer, err := NewSigner(SignMethodSignify, key, "")
+	require.NoError(t, err)
+
+	signify, ok := signer.(*signifySigner)
+	require.True(t, ok)
+
+	signify.maxSize = 4
+
+	var buf bytes.Buffer
+
+	require.NoError(t, signer.Sign(&buf, strings.NewReader("1234")))
+	assert.ErrorIs(t, signer.Sign(&buf, strings.NewReader("12345")), ErrSignFileTooLarge)
+}
+
+// gpgTestKey generates an armored OpenPGP secret key encrypted with testPassphrase.
+func gpgTestKey(t *testing.T) ([]byte, func(content, signature []byte) bool) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", &packet.Config{
+		Algorithm: packet.PubKeyAlgoEdDSA,
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(w, nil))
+	require.NoError(t, w.Close())
+
+	// Read the key again to encrypt it with the self-signatures already in place.
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.NoError(t, entities[0].EncryptPrivateKeys([]byte(testPassphrase), nil))
+
+	buf.Reset()
+
+	w, err = armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entities[0].SerializePrivateWithoutSigning(w, nil))
+	require.NoError(t, w.Close())
+
+	verify := func(content, signature []byte) bool {
+		_, err := openpgp.CheckArmoredDetachedSignature(
+			openpgp.EntityList{entity}, bytes.NewReader(content), bytes.NewReader(signature), nil,
+		)
+
+		return err == nil
+	}
+
+	return buf.Bytes(), verify
+}
+
+// minisignTestKey generates a minisign secret key encrypted with testPassphrase. The key is
+// encrypted with the minimal scrypt cost parameters, as the defaults of minisign.EncryptKey
+// require 1 GiB of memory.
+func minisignTestKey(t *testing.T) ([]byte, func(content, signature []byte) bool) {
+	t.Helper()
+
+	const (
+		ops = 1 << 15
+		mem = 1 << 24
+	)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keyID := make([]byte, 8)
+	salt := make([]byte, 32)
+
+	_, err = rand.Read(keyID)
+	require.NoError(t, err)
+	_, err = rand.Read(salt)
+	require.NoError(t, err)
+
+	plaintext := append(append([]byte{}, keyID...), privateKey...)
+	checksum := blake2b.Sum256(append([]byte("Ed"), plaintext...))
+	plaintext = append(plaintext, checksum[:]...)
+
+	// N=1024, r=8, p=1 are the scrypt parameters minisign derives from ops and mem.
+	keystream, err := scrypt.Key([]byte(testPassphrase), salt, 1024, 8, 1, len(plaintext))
+	require.NoError(t, err)
+
+	for i := range plaintext {
+		plaintext[i] ^= keystream[i]
+	}
+
+	key := append([]byte("EdScB2"), salt...)
+	key = binary.LittleEndian.AppendUint64(key, ops)
+	key = binary.LittleEndian.AppendUint64(key, mem)
+	key = append(key, plaintext...)
+
+	var pub minisign.PublicKey
+
+	require.NoError(t, pub.UnmarshalText([]byte(
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), publicKey...)),
+	)))
+
+	verify := func(content, signature []byte) bool {
+		reader := minisign.NewReader(bytes.NewReader(content))
+
+		if _, err := io.Copy(io.Discard, reader); err != nil {
+			return false
+		}
+
+		return reader.Verify(pub, signature)
+	}
+
+	return []byte("untrusted comment: minisign encrypted secret key\n" + base64.StdEncoding.EncodeToString(key)), verify
+}
+
+// signifyTestKey generates an unencrypted signify secret key as created by `signify -G -n`.
+func signifyTestKey(t *testing.T) ([]byte, func(content, signature []byte) bool) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keyNum := make([]byte, signifyKeyNumSize)
+
+	_, err = rand.Read(keyNum)
+	require.NoError(t, err)
+
+	checksum := sha512.Sum512(privateKey)
+
+	key := append([]byte("EdBK"), make([]byte, 4+signifySaltSize)...)
+	key = append(key, checksum[:signifyChecksumSize]...)
+	key = append(key, keyNum...)
+	key = append(key, privateKey...)
+
+	verify := func(content, signature []byte) bool {
+		lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[0], "untrusted comment: ") {
+			return false
+		}
+
+		data, err := base64.StdEncoding.DecodeString(lines[1])
+		if err != nil || len(data) != 2+signifyKeyNumSize+ed25519.SignatureSize {
+			return false
+		}
+
+		return string(data[:2]) == "Ed" && bytes.Equal(data[2:10], keyNum) &&
+			ed25519.Verify(publicKey, content, data[10:])
+	}
+
+	return []byte("untrusted comment: signify secret key\n" + base64.StdEncoding.EncodeToString(key)), verify
+}
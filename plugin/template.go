@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+	plugin_template "github.com/thegeeklab/wp-plugin-go/v6/template"
+)
+
+// TemplateData provides the data available in tag, title and note templates.
+// It embeds the pipeline metadata, so fields like `.Repository.Name` can be used directly.
+type TemplateData struct {
+	plugin_base.Metadata
+	Tag    string
+	SemVer SemVer
+	Env    map[string]string
+	Files  []string
+}
+
+// AssetTemplateData provides the data available in the asset name template.
+// Dir is the slash-separated directory of the file as matched by the file pattern.
+type AssetTemplateData struct {
+	TemplateData
+	Path string
+	Dir  string
+	Base string
+	Ext  string
+}
+
+// SemVer provides the parts of the release tag if it is a valid semantic version.
+type SemVer struct {
+	Version    string
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease string
+	Metadata   string
+}
+
+// templateData returns the template data for the current plugin state.
+func (p *Plugin) templateData() TemplateData {
+	data := TemplateData{
+		Metadata: p.Metadata,
+		Tag:      p.Settings.Tag,
+		Env:      make(map[string]string),
+		Files:    p.Settings.files,
+	}
+
+	if v, err := semver.NewVersion(p.Settings.Tag); err == nil {
+		data.SemVer = SemVer{
+			Version:    v.String(),
+			Major:      v.Major(),
+			Minor:      v.Minor(),
+			Patch:      v.Patch(),
+			Prerelease: v.Prerelease(),
+			Metadata:   v.Metadata(),
+		}
+	}
+
+	for _, env := range os.Environ() {
+		if key, value, ok := strings.Cut(env, "="); ok {
+			data.Env[key] = value
+		}
+	}
+
+	return data
+}
+
+// render renders the given template string with the current template data.
+func (p *Plugin) render(tmpl string) (string, error) {
+	return p.renderWith(tmpl, p.templateData())
+}
+
+// renderText renders the title or note if the Template setting is enabled. Otherwise, the
+// text is used as is, so existing notes containing template delimiters are not changed.
+func (p *Plugin) renderText(text string) (string, error) {
+	if !p.Settings.Template {
+		return text, nil
+	}
+
+	return p.render(text)
+}
+
+// renderWith renders the given template string with the given data.
+func (p *Plugin) renderWith(tmpl string, data any) (string, error) {
+	if tmpl == "" {
+		return "", nil
+	}
+
+	out, err := plugin_template.RenderTrim(p.Network.Context, *p.Network.Client, tmpl, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return out, nil
+}
+
+// assetName renders the asset name for the given file. The name template of the first
+// file mapping that matched the file takes precedence over the AssetName template.
+// Without a template, the base name of the file is used.
+func (p *Plugin) assetName(file string) (string, error) {
+	tmpl := p.Settings.AssetName
+
+	if name, ok := p.Settings.fileNames[file]; ok {
+		tmpl = name
+	}
+
+	if tmpl == "" {
+		return filepath.Base(file), nil
+	}
+
+	name, err := p.renderWith(tmpl, AssetTemplateData{
+		TemplateData: p.templateData(),
+		Path:         filepath.ToSlash(file),
+		Dir:          filepath.ToSlash(filepath.Dir(file)),
+		Base:         filepath.Base(file),
+		Ext:          filepath.Ext(file),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("%w: %q for %s", ErrAssetNameInvalid, name, file)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
+)
+
+func TestRender(t *testing.T) {
+	t.Setenv("RELEASE_CODENAME", "bookworm")
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		tag     string
+		files   []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "plain string",
+			tmpl: "Release v1.2.3",
+			tag:  "v1.2.3",
+			want: "Release v1.2.3",
+		},
+		{
+			name: "repository and tag",
+			tmpl: `{{ .Repository.Name }} {{ .Tag | trimPrefix "v" }}`,
+			tag:  "v1.2.3",
+			want: "app 1.2.3",
+		},
+		{
+			name: "semver parts",
+			tmpl: "{{ .SemVer.Major }}.{{ .SemVer.Minor }} ({{ .SemVer.Prerelease }})",
+			tag:  "v1.2.3-rc.1",
+			want: "1.2 (rc.1)",
+		},
+		{
+			name: "invalid semver",
+			tmpl: "{{ .SemVer.Version }}",
+			tag:  "nightly",
+			want: "",
+		},
+		{
+			name: "environment variables",
+			tmpl: "{{ .Env.RELEASE_CODENAME }}",
+			tag:  "v1.2.3",
+			want: "bookworm",
+		},
+		{
+			name:  "files",
+			tmpl:  "{{ range .Files }}- {{ base . }}\n{{ end }}",
+			tag:   "v1.2.3",
+			files: []string{"dist/app-linux", "dist/app-darwin"},
+			want:  "- app-linux\n- app-darwin",
+		},
+		{
+			name:    "invalid template",
+			tmpl:    "{{ .Tag ",
+			tag:     "v1.2.3",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(&Settings{
+				Tag:   tt.tag,
+				files: tt.files,
+			})
+			p.Metadata.Repository.Name = "app"
+
+			got, err := p.render(tt.tmpl)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		names   map[string]string
+		file    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "file name without template",
+			file: "dist/linux/app",
+			want: "app",
+		},
+		{
+			name: "directory prefix",
+			tmpl: `{{ .Dir | replace "/" "-" }}-{{ .Base }}`,
+			file: "dist/linux/app",
+			want: "dist-linux-app",
+		},
+		{
+			name: "tag and extension",
+			tmpl: `{{ trimSuffix .Ext .Base }}-{{ .Tag }}{{ .Ext }}`,
+			file: "dist/app.tar.gz",
+			want: "app.tar-v1.2.3.gz",
+		},
+		{
+			name:  "file mapping",
+			tmpl:  "{{ .Base }}",
+			names: map[string]string{"dist/out.bin": "app-{{ .Tag }}-linux-amd64"},
+			file:  "dist/out.bin",
+			want:  "app-v1.2.3-linux-amd64",
+		},
+		{
+			name:  "file mapping does not match",
+			names: map[string]string{"dist/out.bin": "app"},
+			file:  "dist/out.txt",
+			want:  "out.txt",
+		},
+		{
+			name:    "name with path separator",
+			tmpl:    "{{ .Path }}",
+			file:    "dist/linux/app",
+			wantErr: ErrAssetNameInvalid,
+		},
+		{
+			name:    "empty name",
+			tmpl:    `{{ .Base | trimPrefix "app" }}`,
+			file:    "dist/linux/app",
+			wantErr: ErrAssetNameInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(&Settings{
+				Tag:       "v1.2.3",
+				AssetName: tt.tmpl,
+				fileNames: tt.names,
+			})
+
+			got, err := p.assetName(tt.file)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewAssets(t *testing.T) {
+	p := newTestPlugin(&Settings{
+		Tag:       "v1.2.3",
+		AssetName: `{{ .Base }}-{{ len .Files }}`,
+	})
+
+	assets, err := p.newAssets([]string{"dist/app", "dist/lib"})
+	assert.NoError(t, err)
+	assert.Equal(t, []gitea.Asset{
+		{Path: "dist/app", Name: "app-2"},
+		{Path: "dist/lib", Name: "lib-2"},
+	}, assets)
+}
@@ -1,67 +1,206 @@
 package plugin
 
 import (
-	"crypto/md5"  //nolint:gosec
-	"crypto/sha1" //nolint:gosec
-	"crypto/sha256"
-	"crypto/sha512"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"hash/adler32"
-	"hash/crc32"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
-	"golang.org/x/crypto/blake2b"
-	"golang.org/x/crypto/blake2s"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
 )
 
-var ErrHashMethodNotSupported = errors.New("hash method not supported")
+var (
+	ErrHashMethodNotSupported = errors.New("hash method not supported")
+	ErrChecksumFormatInvalid  = errors.New("invalid checksum_format value")
+	ErrChecksumStyleInvalid   = errors.New("invalid checksum_style value")
+	ErrChecksumFileInvalid    = errors.New("invalid checksum_file value")
+)
+
+const (
+	ChecksumFormatCombined = "combined"
+	ChecksumFormatPerFile  = "per-file"
+	ChecksumFormatBoth     = "both"
+
+	ChecksumStyleGNU = "gnu"
+	ChecksumStyleBSD = "bsd"
+
+	// DefaultChecksumFileName is the name of combined checksum files for hash methods
+	// without a SumFile, e.g. "adler32sum.txt".
+	DefaultChecksumFileName = "{method}sum.txt"
+
+	checksumDirPerm = 0o755
+)
 
 // Checksum calculates the checksum of the given io.Reader using the specified hash method.
-// Supported hash methods are: "md5", "sha1", "sha256", "sha512", "adler32", "crc32", "blake2b", "blake2s".
+// See HashMethods for the supported hash methods.
 func Checksum(r io.Reader, method string) (string, error) {
-	b, err := io.ReadAll(r)
+	sums, err := Checksums(r, []string{method})
 	if err != nil {
 		return "", err
 	}
 
-	switch method {
-	case "md5":
-		//nolint:gosec
-		return fmt.Sprintf("%x", md5.Sum(b)), nil
-	case "sha1":
-		//nolint:gosec
-		return fmt.Sprintf("%x", sha1.Sum(b)), nil
-	case "sha256":
-		return fmt.Sprintf("%x", sha256.Sum256(b)), nil
-	case "sha512":
-		return fmt.Sprintf("%x", sha512.Sum512(b)), nil
-	case "adler32":
-		return fmt.Sprintf("%08x", adler32.Checksum(b)), nil
-	case "crc32":
-		return fmt.Sprintf("%08x", crc32.ChecksumIEEE(b)), nil
-	case "blake2b":
-		return fmt.Sprintf("%x", blake2b.Sum256(b)), nil
-	case "blake2s":
-		return fmt.Sprintf("%x", blake2s.Sum256(b)), nil
-	}
-
-	return "", fmt.Errorf("%w: %q", ErrHashMethodNotSupported, method)
+	return sums[0], nil
 }
 
-// WriteChecksums calculates the checksums for the given files using the specified hash methods,
-// and writes the checksums to files named after the hash methods (e.g. "md5sum.txt", "sha256sum.txt").
-func WriteChecksums(files, methods []string, outDir string) ([]string, error) {
-	if len(files) == 0 {
-		return files, nil
+// Checksums calculates the checksums of the given io.Reader for all specified hash methods.
+// The content is streamed through all hashes in a single pass, so memory usage does not
+// depend on the size of the input. The checksums are returned in the order of the methods.
+func Checksums(r io.Reader, methods []string) ([]string, error) {
+	hashes := make([]hash.Hash, 0, len(methods))
+	writers := make([]io.Writer, 0, len(methods))
+
+	for _, method := range methods {
+		h, err := newHash(method)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes = append(hashes, h)
+		writers = append(writers, h)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), r); err != nil {
+		return nil, err
 	}
 
-	checksumFiles := make([]string, 0)
+	sums := make([]string, 0, len(hashes))
+
+	for _, h := range hashes {
+		sums = append(sums, hex.EncodeToString(h.Sum(nil)))
+	}
+
+	return sums, nil
+}
+
+// ChecksumOptions configures the checksum files written by WriteChecksums.
+type ChecksumOptions struct {
+	// Methods are the hash methods to calculate.
+	Methods []string
+	// Format is one of "combined", "per-file" or "both". Combined files are named by FileName,
+	// per-file sidecars after the asset and the hash method (e.g. "app.tar.gz.sha256").
+	// Defaults to "combined".
+	Format string
+	// Style is the line format, either "gnu" ("<sum>  <file>") or BSD-style tagged
+	// output "bsd" ("SHA256 (<file>) = <sum>"). Defaults to "gnu".
+	Style string
+	// OutDir is the directory for the checksum files. It is created if it does not exist.
+	// Per-file sidecars are written next to the file if OutDir is empty.
+	OutDir string
+	// FileName is the name of the combined checksum files. The placeholders "{method}" and
+	// "{METHOD}" are replaced by the hash method. Defaults to the SumFile of the hash method.
+	FileName string
+	// Sums caches the checksums of the assets. Cached checksums are reused and the
+	// calculated checksums are added, so they can be reused by the SBOM and provenance.
+	Sums AssetSums
+}
+
+// AssetSums caches the checksums of files by path and hash method, so every file is read
+// only once even if its checksums are used for checksum files, SBOM and provenance.
+type AssetSums map[string]map[string]string
+
+// Get returns the checksums of the file in the order of the methods. Checksums that are
+// not cached yet are calculated in a single pass and added to the cache.
+func (s AssetSums) Get(file string, methods []string) ([]string, error) {
+	cached := s[file]
+	missing := make([]string, 0, len(methods))
 
 	for _, method := range methods {
-		checksumFile := filepath.Join(outDir, method+"sum.txt")
+		if _, ok := cached[method]; !ok && !slices.Contains(missing, method) {
+			missing = append(missing, method)
+		}
+	}
+
+	if len(missing) > 0 {
+		sums, err := checksumFile(file, missing)
+		if err != nil {
+			return nil, err
+		}
+
+		if cached == nil {
+			cached = make(map[string]string, len(missing))
+			s[file] = cached
+		}
+
+		for i, method := range missing {
+			cached[method] = sums[i]
+		}
+	}
+
+	result := make([]string, 0, len(methods))
+
+	for _, method := range methods {
+		result = append(result, cached[method])
+	}
+
+	return result, nil
+}
+
+// Add calculates the checksums of the assets for the given methods in a single pass per file.
+func (s AssetSums) Add(assets []gitea.Asset, methods []string) error {
+	for _, asset := range assets {
+		if _, err := s.Get(asset.Path, methods); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteChecksums calculates the checksums for the given assets using the specified hash methods
+// and writes them to combined and/or per-file checksum files according to the format.
+// The checksum files list the asset names, so they can be verified after downloading the assets.
+// Each file is read only once for all hash methods and not at all if its checksums are
+// cached in Sums already. It returns the given assets followed by
+// the written checksum files.
+func WriteChecksums(assets []gitea.Asset, opt ChecksumOptions) ([]gitea.Asset, error) {
+	if len(assets) == 0 || len(opt.Methods) == 0 {
+		return assets, nil
+	}
+
+	combined := opt.Format == "" || opt.Format == ChecksumFormatCombined || opt.Format == ChecksumFormatBoth
+	perFile := opt.Format == ChecksumFormatPerFile || opt.Format == ChecksumFormatBoth
+
+	if !combined && !perFile {
+		return nil, fmt.Errorf("%w: %q", ErrChecksumFormatInvalid, opt.Format)
+	}
+
+	if opt.Style != "" && opt.Style != ChecksumStyleGNU && opt.Style != ChecksumStyleBSD {
+		return nil, fmt.Errorf("%w: %q", ErrChecksumStyleInvalid, opt.Style)
+	}
+
+	if err := ValidateChecksumFileName(opt.FileName, opt.Methods); err != nil {
+		return nil, err
+	}
+
+	if opt.Sums == nil {
+		opt.Sums = AssetSums{}
+	}
+
+	if opt.OutDir != "" {
+		if err := os.MkdirAll(opt.OutDir, checksumDirPerm); err != nil {
+			return nil, fmt.Errorf("failed to create checksum directory: %w", err)
+		}
+	}
+
+	checksumFiles := make([]gitea.Asset, 0)
+	writers := make([]io.Writer, 0, len(opt.Methods))
+
+	for _, method := range opt.Methods {
+		if _, err := newHash(method); err != nil {
+			return nil, err
+		}
+
+		if !combined {
+			continue
+		}
+
+		name := checksumFileName(opt.FileName, method)
+		checksumFile := filepath.Join(opt.OutDir, name)
 
 		f, err := os.Create(checksumFile)
 		if err != nil {
@@ -69,26 +208,108 @@ func WriteChecksums(files, methods []string, outDir string) ([]string, error) {
 		}
 		defer f.Close()
 
-		for _, file := range files {
-			handle, err := os.Open(file)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read %q artifact: %w", file, err)
-			}
-			defer handle.Close()
+		checksumFiles = append(checksumFiles, gitea.Asset{Path: checksumFile, Name: name})
+		writers = append(writers, f)
+	}
+
+	sidecars := make([]gitea.Asset, 0)
 
-			hash, err := Checksum(handle, method)
-			if err != nil {
-				return nil, fmt.Errorf("could not checksum %q file: %w", file, err)
+	for _, asset := range assets {
+		sums, err := opt.Sums.Get(asset.Path, opt.Methods)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, sum := range sums {
+			if combined {
+				if err := writeChecksumLine(writers[i], opt.Style, opt.Methods[i], sum, asset.Name); err != nil {
+					return nil, err
+				}
 			}
 
-			_, err = fmt.Fprintf(f, "%s  %s\n", hash, file) //#nosec G705
-			if err != nil {
-				return nil, err
+			if perFile {
+				sidecar, err := writeSidecar(opt, opt.Methods[i], sum, asset)
+				if err != nil {
+					return nil, err
+				}
+
+				sidecars = append(sidecars, sidecar)
 			}
 		}
+	}
+
+	return append(append(assets, checksumFiles...), sidecars...), nil
+}
+
+// ValidateChecksumFileName returns an error if the combined checksum files of
+// multiple hash methods would have the same name.
+func ValidateChecksumFileName(name string, methods []string) error {
+	if name != "" && len(methods) > 1 && !strings.Contains(name, "{method}") && !strings.Contains(name, "{METHOD}") {
+		return fmt.Errorf("%w: %q requires a {method} placeholder for multiple methods", ErrChecksumFileInvalid, name)
+	}
+
+	return nil
+}
+
+func checksumFileName(name, method string) string {
+	if name == "" {
+		name = hashMethods[method].SumFile
+	}
+
+	if name == "" {
+		name = DefaultChecksumFileName
+	}
+
+	return strings.NewReplacer("{method}", method, "{METHOD}", strings.ToUpper(method)).Replace(name)
+}
+
+// writeSidecar writes the checksum of a single asset to "<name>.<method>". The sidecar
+// references the asset by its name, so it can be verified next to the downloaded file.
+func writeSidecar(opt ChecksumOptions, method, sum string, asset gitea.Asset) (gitea.Asset, error) {
+	dir := opt.OutDir
+	if dir == "" {
+		dir = filepath.Dir(asset.Path)
+	}
+
+	name := asset.Name + "." + method
+	sidecar := filepath.Join(dir, name)
+
+	f, err := os.Create(sidecar)
+	if err != nil {
+		return gitea.Asset{}, err
+	}
+	defer f.Close()
 
-		checksumFiles = append(checksumFiles, checksumFile)
+	if err := writeChecksumLine(f, opt.Style, method, sum, asset.Name); err != nil {
+		return gitea.Asset{}, err
+	}
+
+	return gitea.Asset{Path: sidecar, Name: name}, nil
+}
+
+func writeChecksumLine(w io.Writer, style, method, sum, file string) error {
+	var err error
+
+	if style == ChecksumStyleBSD {
+		_, err = fmt.Fprintf(w, "%s (%s) = %s\n", hashMethods[method].Tag, file, sum) //#nosec G705
+	} else {
+		_, err = fmt.Fprintf(w, "%s  %s\n", sum, file) //#nosec G705
+	}
+
+	return err
+}
+
+func checksumFile(file string, methods []string) ([]string, error) {
+	handle, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q artifact: %w", file, err)
+	}
+	defer handle.Close()
+
+	sums, err := Checksums(handle, methods)
+	if err != nil {
+		return nil, fmt.Errorf("could not checksum %q file: %w", file, err)
 	}
 
-	return append(files, checksumFiles...), nil
+	return sums, nil
 }
@@ -5,10 +5,12 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-gitea-release/gitea"
 )
 
 //nolint:lll
@@ -120,6 +122,119 @@ func BenchmarkChecksum(b *testing.B) {
 	}
 }
 
+func TestChecksums(t *testing.T) {
+	methods := []string{"md5", "sha1", "sha256", "sha512", "adler32", "crc32", "blake2b", "blake2s"}
+
+	sums, err := Checksums(bytes.NewReader([]byte("hello")), methods)
+	assert.NoError(t, err)
+	assert.Len(t, sums, len(methods))
+
+	for i, method := range methods {
+		want, err := Checksum(bytes.NewReader([]byte("hello")), method)
+		assert.NoError(t, err)
+		assert.Equal(t, want, sums[i], method)
+	}
+
+	_, err = Checksums(bytes.NewReader([]byte("hello")), []string{"sha256", "unsupported"})
+	assert.ErrorIs(t, err, ErrHashMethodNotSupported)
+}
+
+func TestAssetSums(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app")
+	assert.NoError(t, os.WriteFile(file, []byte("hello"), 0o600))
+
+	sums := AssetSums{}
+
+	got, err := sums.Get(file, []string{"sha256", "md5"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		"5d41402abc4b2a76b9719d911017c592",
+	}, got)
+
+	// Cached checksums are returned without reading the file again.
+	assert.NoError(t, os.Remove(file))
+
+	got, err = sums.Get(file, []string{"md5"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"5d41402abc4b2a76b9719d911017c592"}, got)
+
+	_, err = sums.Get(file, []string{"sha1"})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// zeroReader is an io.Reader that returns an infinite stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+
+	return len(p), nil
+}
+
+func TestChecksumsMemory(t *testing.T) {
+	const (
+		size     = 64 << 20
+		maxAlloc = 1 << 20
+	)
+
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	_, err := Checksums(io.LimitReader(zeroReader{}, size), []string{"md5", "sha256", "crc32"})
+	assert.NoError(t, err)
+
+	runtime.ReadMemStats(&after)
+
+	// The input is streamed, so the allocations do not grow with the input size.
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(maxAlloc))
+}
+
+func BenchmarkChecksums(b *testing.B) {
+	input := bytes.Repeat([]byte("hello"), 1<<20)
+	methods := []string{"md5", "sha256", "sha512", "crc32"}
+
+	b.Run("single pass", func(b *testing.B) {
+		b.SetBytes(int64(len(input)))
+		b.ReportAllocs()
+
+		for b.Loop() {
+			_, _ = Checksums(bytes.NewReader(input), methods)
+		}
+	})
+
+	b.Run("pass per method", func(b *testing.B) {
+		b.SetBytes(int64(len(input)))
+		b.ReportAllocs()
+
+		for b.Loop() {
+			for _, method := range methods {
+				_, _ = Checksum(bytes.NewReader(input), method)
+			}
+		}
+	})
+}
+
+func BenchmarkWriteChecksums(b *testing.B) {
+	tempDir := b.TempDir()
+	file := filepath.Join(tempDir, "file.bin")
+
+	if err := os.WriteFile(file, bytes.Repeat([]byte("hello"), 1<<20), 0o600); err != nil {
+		b.Fatalf("failed to create test file: %v", err)
+	}
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		_, _ = WriteChecksums(gitea.NewAssets([]string{file}), ChecksumOptions{
+			Methods: []string{"md5", "sha256", "sha512"},
+			OutDir:  tempDir,
+		})
+	}
+}
+
 func TestWriteChecksums(t *testing.T) {
 	tempDir := t.TempDir()
 	files := []string{
@@ -188,13 +303,18 @@ func TestWriteChecksums(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := WriteChecksums(tt.files, tt.methods, tt.tempDir)
+			assets, err := WriteChecksums(gitea.NewAssets(tt.files), ChecksumOptions{Methods: tt.methods, OutDir: tt.tempDir})
 			if tt.wantErr {
 				assert.Error(t, err)
 
 				return
 			}
 
+			result := make([]string, 0, len(assets))
+			for _, asset := range assets {
+				result = append(result, asset.Path)
+			}
+
 			assert.NoError(t, err)
 			sort.Strings(result)
 			sort.Strings(tt.want)
@@ -202,3 +322,98 @@ func TestWriteChecksums(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteChecksumsFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		style     string
+		fileName  string
+		wantFiles map[string]string
+		wantErr   error
+	}{
+		{
+			name:   "combined",
+			format: "combined",
+			wantFiles: map[string]string{
+				"sha256sum.txt": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  app-linux-amd64.tar.gz\n",
+			},
+		},
+		{
+			name:     "custom file name",
+			format:   "combined",
+			fileName: "{METHOD}SUMS",
+			wantFiles: map[string]string{
+				"MD5SUMS": "5d41402abc4b2a76b9719d911017c592  app-linux-amd64.tar.gz\n",
+			},
+		},
+		{
+			name:     "file name without placeholder",
+			fileName: "checksums.txt",
+			wantErr:  ErrChecksumFileInvalid,
+		},
+		{
+			name:   "per file",
+			format: "per-file",
+			wantFiles: map[string]string{
+				"app-linux-amd64.tar.gz.md5":    "5d41402abc4b2a76b9719d911017c592  app-linux-amd64.tar.gz\n",
+				"app-linux-amd64.tar.gz.sha256": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  app-linux-amd64.tar.gz\n",
+			},
+		},
+		{
+			name:   "both bsd style",
+			format: "both",
+			style:  "bsd",
+			wantFiles: map[string]string{
+				"md5sum.txt":                    "MD5 (app-linux-amd64.tar.gz) = 5d41402abc4b2a76b9719d911017c592\n",
+				"app-linux-amd64.tar.gz.sha256": "SHA256 (app-linux-amd64.tar.gz) = 2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824\n",
+			},
+		},
+		{
+			name:    "invalid format",
+			format:  "sidecar",
+			wantErr: ErrChecksumFormatInvalid,
+		},
+		{
+			name:    "invalid style",
+			style:   "openssl",
+			wantErr: ErrChecksumStyleInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "app.tar.gz")
+
+			assert.NoError(t, os.WriteFile(file, []byte("hello"), 0o600))
+
+			assets := []gitea.Asset{{Path: file, Name: "app-linux-amd64.tar.gz"}}
+
+			result, err := WriteChecksums(assets, ChecksumOptions{
+				Methods:  []string{"md5", "sha256"},
+				Format:   tt.format,
+				Style:    tt.style,
+				OutDir:   filepath.Join(dir, "checksums"),
+				FileName: tt.fileName,
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			for name, want := range tt.wantFiles {
+				path := filepath.Join(dir, "checksums", name)
+
+				assert.Contains(t, result, gitea.Asset{Path: path, Name: name})
+
+				got, err := os.ReadFile(path)
+				assert.NoError(t, err)
+				assert.Equal(t, want, string(got))
+			}
+		})
+	}
+}